@@ -0,0 +1,175 @@
+package bitarray
+
+import (
+	"math/bits"
+	"sync/atomic"
+)
+
+// An Allocator hands out integer IDs in [0, Size()) from a bitmap, where a set bit marks
+// an ID in use. All its methods are safe to call concurrently: IDs are claimed with a
+// compare-and-swap on the block holding them, and a hint remembers the block the last ID
+// was claimed from or freed to, so that searches need not start from zero.
+type Allocator struct {
+	ba    BitArray
+	hint  atomic.Int64 // index of the block to start searching from
+	inuse atomic.Int64
+}
+
+// NewAllocator creates an Allocator of n IDs, all of them free.
+func NewAllocator(n int) *Allocator {
+	return &Allocator{ba: New(n)}
+}
+
+// Size returns the no. of IDs managed by the allocator.
+func (a *Allocator) Size() int { return a.ba.n }
+
+// InUse returns the no. of IDs currently allocated.
+func (a *Allocator) InUse() int { return int(a.inuse.Load()) }
+
+// Alloc claims a free ID. ok is false if all the IDs are in use.
+func (a *Allocator) Alloc() (id int, ok bool) {
+	nblk := len(a.ba.bits)
+	h := int(a.hint.Load())
+	for i := 0; i < nblk; i++ {
+		bi := (h + i) % nblk
+		u, m := &a.ba.bits[bi], a.mask(bi)
+		for {
+			o := atomic.LoadUint64(u)
+			free := ^o & m
+			if free == 0 {
+				break
+			}
+			si := bits.TrailingZeros64(free)
+			if atomic.CompareAndSwapUint64(u, o, o|1<<si) {
+				if bi != h {
+					a.hint.Store(int64(bi))
+				}
+				a.inuse.Add(1)
+				return bi*64 + si, true
+			}
+		}
+	}
+	return -1, false
+}
+
+// AllocN claims n contiguous free IDs and returns the first of them.
+// ok is false if there is no run of n free IDs.
+func (a *Allocator) AllocN(n int) (id int, ok bool) {
+	if n <= 0 || n > a.ba.n {
+		return -1, false
+	}
+	if n == 1 {
+		return a.Alloc()
+	}
+
+	// search from the hint to the end, then from the start up to the hint
+	h := int(a.hint.Load()) * 64
+	for _, r := range [][2]int{{h, a.ba.n}, {0, min(h+n-1, a.ba.n)}} {
+		for k := r[0]; ; k++ {
+			if k = a.findRun(k, r[1], n); k < 0 {
+				break
+			}
+			if a.claim(k, n) {
+				a.hint.Store(int64((k + n - 1) / 64))
+				a.inuse.Add(int64(n))
+				return k, true
+			}
+		}
+	}
+	return -1, false
+}
+
+// Free releases the ID id. It panics if id is not in use.
+func (a *Allocator) Free(id int) {
+	if !a.ba.AtomicChkClr(id) {
+		panic("bitarray: free of an unallocated id")
+	}
+	if bi := int64(id / 64); a.hint.Load() != bi {
+		a.hint.Store(bi)
+	}
+	a.inuse.Add(-1)
+}
+
+// FreeN releases the n IDs starting at id, as claimed by AllocN. It panics if any of them is not in use.
+func (a *Allocator) FreeN(id, n int) {
+	for k := id; k < id+n; k++ {
+		a.Free(k)
+	}
+}
+
+// mask returns the bits of block bi that map to IDs.
+func (a *Allocator) mask(bi int) uint64 {
+	if bi == len(a.ba.bits)-1 {
+		return lomask(a.ba.n - 64*bi)
+	}
+	return ^uint64(0)
+}
+
+// findRun returns the start of the first run of n clear bits in [from, to), or -1.
+// The bits may change while it looks, so the run is only a candidate for claim.
+func (a *Allocator) findRun(from, to, n int) int {
+	for k := from; k+n <= to; {
+		c := a.next(k, to, 0)
+		if c < 0 || c+n > to {
+			return -1
+		}
+		s := a.next(c, c+n, 1)
+		if s < 0 {
+			return c
+		}
+		k = s + 1
+	}
+	return -1
+}
+
+// next returns the position of the first bit in [from, to) with value v, or -1.
+func (a *Allocator) next(from, to int, v Bit) int {
+	for k := from; k < to; {
+		bi, si := biandsi(k)
+		u := atomic.LoadUint64(&a.ba.bits[bi])
+		if v == 0 {
+			u = ^u
+		}
+		if u >>= si; u != 0 {
+			if k += bits.TrailingZeros64(u); k < to {
+				return k
+			}
+			return -1
+		}
+		k += 64 - int(si)
+	}
+	return -1
+}
+
+// claim atomically sets the n bits starting at k, a block at a time. If any of them
+// turns out to be set already, it clears the bits it has set and returns false.
+func (a *Allocator) claim(k, n int) bool {
+	for j := 0; j < n; {
+		bi, si := biandsi(k + j)
+		w := min(n-j, 64-int(si))
+		m := lomask(w) << si
+		u := &a.ba.bits[bi]
+		for {
+			o := atomic.LoadUint64(u)
+			if o&m != 0 {
+				a.release(k, j)
+				return false
+			}
+			if atomic.CompareAndSwapUint64(u, o, o|m) {
+				break
+			}
+		}
+		j += w
+	}
+	return true
+}
+
+// release clears the n bits starting at k, a block at a time.
+func (a *Allocator) release(k, n int) {
+	for j := 0; j < n; {
+		bi, si := biandsi(k + j)
+		w := min(n-j, 64-int(si))
+		atomic.AndUint64(&a.ba.bits[bi], ^(lomask(w) << si))
+		j += w
+	}
+}
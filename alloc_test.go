@@ -0,0 +1,179 @@
+package bitarray
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAllocator(t *testing.T) {
+	t.Run("exhaust", func(t *testing.T) {
+		for _, n := range []int{0, 1, 63, 64, 65, 1000} {
+			a := NewAllocator(n)
+			seen := make([]bool, n)
+			for i := 0; i < n; i++ {
+				id, ok := a.Alloc()
+				if !ok || id < 0 || id >= n || seen[id] {
+					t.Fatalf("Test %d failed. got = %d, %t\n", n, id, ok)
+				}
+				seen[id] = true
+			}
+			if id, ok := a.Alloc(); ok {
+				t.Fatalf("Test %d failed. got = %d, exp no free id\n", n, id)
+			}
+			if a.InUse() != n {
+				t.Fatalf("Test %d failed. got = %d, exp = %d\n", n, a.InUse(), n)
+			}
+			if n == 0 {
+				continue
+			}
+			a.Free(n / 2)
+			if id, ok := a.Alloc(); !ok || id != n/2 {
+				t.Fatalf("Test %d failed. got = %d, %t, exp = %d\n", n, id, ok, n/2)
+			}
+		}
+	})
+
+	t.Run("alloc-n", func(t *testing.T) {
+		a := NewAllocator(300)
+		for i := 0; i < 10; i++ {
+			a.Alloc()
+		}
+		a.Free(3)
+		a.Free(4)
+
+		id, ok := a.AllocN(2)
+		if !ok || id != 3 {
+			t.Fatalf("Test failed. got = %d, %t, exp = %d\n", id, ok, 3)
+		}
+		// the run must not fit before position 10 and has to cross a block boundary
+		id, ok = a.AllocN(100)
+		if !ok || id != 10 {
+			t.Fatalf("Test failed. got = %d, %t, exp = %d\n", id, ok, 10)
+		}
+		for k := 0; k < 110; k++ {
+			if !a.ba.Chk(k) {
+				t.Fatalf("Test failed. id %d is not in use\n", k)
+			}
+		}
+		if _, ok = a.AllocN(191); ok {
+			t.Fatalf("Test failed. expected no run of 191 free ids\n")
+		}
+		if id, ok = a.AllocN(190); !ok || id != 110 {
+			t.Fatalf("Test failed. got = %d, %t, exp = %d\n", id, ok, 110)
+		}
+		a.FreeN(10, 100)
+		if a.InUse() != 200 {
+			t.Fatalf("Test failed. got = %d, exp = %d\n", a.InUse(), 200)
+		}
+		if id, ok = a.AllocN(64); !ok || id != 10 {
+			t.Fatalf("Test failed. got = %d, %t, exp = %d\n", id, ok, 10)
+		}
+	})
+
+	t.Run("double free", func(t *testing.T) {
+		a := NewAllocator(10)
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("Test failed. expected a panic\n")
+			}
+		}()
+		a.Free(3)
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		const n = 500
+		a := NewAllocator(n)
+		owner := make([]atomic.Int32, n)
+		var wg sync.WaitGroup
+		for g := 1; g <= max(4, runtime.GOMAXPROCS(0)); g++ {
+			wg.Add(1)
+			go func(g int32) {
+				defer wg.Done()
+				var mine []int
+				for i := 0; i < 2000; i++ {
+					if i%3 == 2 && len(mine) != 0 {
+						id := mine[len(mine)-1]
+						mine = mine[:len(mine)-1]
+						owner[id].Store(0)
+						a.Free(id)
+						continue
+					}
+					if i%7 == 0 {
+						id, ok := a.AllocN(5)
+						if !ok {
+							continue
+						}
+						for k := id; k < id+5; k++ {
+							if !owner[k].CompareAndSwap(0, g) {
+								t.Errorf("Test failed. id %d handed out twice\n", k)
+								return
+							}
+							mine = append(mine, k)
+						}
+						continue
+					}
+					id, ok := a.Alloc()
+					if !ok {
+						continue
+					}
+					if !owner[id].CompareAndSwap(0, g) {
+						t.Errorf("Test failed. id %d handed out twice\n", id)
+						return
+					}
+					mine = append(mine, id)
+				}
+				for _, id := range mine {
+					owner[id].Store(0)
+					a.Free(id)
+				}
+			}(int32(g))
+		}
+		wg.Wait()
+		if a.InUse() != 0 || a.ba.Cnt() != 0 {
+			t.Fatalf("Test failed. got = %d, %d, exp = %d\n", a.InUse(), a.ba.Cnt(), 0)
+		}
+	})
+}
+
+func BenchmarkAllocator(b *testing.B) {
+	b.Run("alloc-free, contended", func(b *testing.B) {
+		b.ReportAllocs()
+		a := NewAllocator(1 << 16)
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if id, ok := a.Alloc(); ok {
+					a.Free(id)
+				}
+			}
+		})
+	})
+
+	b.Run("alloc-free, mostly full", func(b *testing.B) {
+		b.ReportAllocs()
+		a := NewAllocator(1 << 16)
+		for i := 0; i < a.Size()-64; i++ {
+			a.Alloc()
+		}
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if id, ok := a.Alloc(); ok {
+					a.Free(id)
+				}
+			}
+		})
+	})
+
+	b.Run("alloc-n", func(b *testing.B) {
+		b.ReportAllocs()
+		a := NewAllocator(1 << 16)
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if id, ok := a.AllocN(10); ok {
+					a.FreeN(id, 10)
+				}
+			}
+		})
+	})
+}
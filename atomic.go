@@ -0,0 +1,77 @@
+package bitarray
+
+import "sync/atomic"
+
+// The Atomic methods below read and modify the bits with atomic operations on the
+// underlying uint64 blocks, so that goroutines may concurrently modify different bits
+// of the same block, or race on the same bit, without losing updates. They are safe to
+// call concurrently with each other, but not with the non-atomic methods or with
+// methods that change the size of the array.
+
+// AtomicSet atomically sets the bit at position k.
+func (ba *BitArray) AtomicSet(k int) {
+	ba.assertIdx(k)
+	bi, si := biandsi(k)
+	atomic.OrUint64(&ba.bits[bi], 1<<si)
+}
+
+// AtomicClr atomically clears the bit at position k.
+func (ba *BitArray) AtomicClr(k int) {
+	ba.assertIdx(k)
+	bi, si := biandsi(k)
+	atomic.AndUint64(&ba.bits[bi], ^(1 << si))
+}
+
+// AtomicTgl atomically toggles the bit at position k.
+func (ba *BitArray) AtomicTgl(k int) {
+	ba.assertIdx(k)
+	bi, si := biandsi(k)
+	u := &ba.bits[bi]
+	for {
+		o := atomic.LoadUint64(u)
+		if atomic.CompareAndSwapUint64(u, o, o^(1<<si)) {
+			return
+		}
+	}
+}
+
+// AtomicChk atomically returns the value of the bit at position k.
+func (ba *BitArray) AtomicChk(k int) bool {
+	ba.assertIdx(k)
+	bi, si := biandsi(k)
+	return chk(atomic.LoadUint64(&ba.bits[bi]), si) != 0
+}
+
+// AtomicChkSet atomically sets the bit at position k and returns its value before setting it.
+// Exactly one of several goroutines racing to set the same clear bit sees false.
+func (ba *BitArray) AtomicChkSet(k int) bool {
+	ba.assertIdx(k)
+	bi, si := biandsi(k)
+	return chk(atomic.OrUint64(&ba.bits[bi], 1<<si), si) != 0
+}
+
+// AtomicChkClr atomically clears the bit at position k and returns its value before clearing it.
+// Exactly one of several goroutines racing to clear the same set bit sees true.
+func (ba *BitArray) AtomicChkClr(k int) bool {
+	ba.assertIdx(k)
+	bi, si := biandsi(k)
+	return chk(atomic.AndUint64(&ba.bits[bi], ^(1<<si)), si) != 0
+}
+
+// CompareAndSwapBit atomically sets the bit at position k to nv if its current value is ov,
+// and reports whether it did. Only the lowest bit of ov and nv is used.
+func (ba *BitArray) CompareAndSwapBit(k int, ov, nv Bit) bool {
+	ba.assertIdx(k)
+	ov, nv = ov&1, nv&1
+	bi, si := biandsi(k)
+	u := &ba.bits[bi]
+	for {
+		o := atomic.LoadUint64(u)
+		if chk(o, si) != ov {
+			return false
+		}
+		if ov == nv || atomic.CompareAndSwapUint64(u, o, o&^(1<<si)|nv<<si) {
+			return true
+		}
+	}
+}
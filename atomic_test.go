@@ -0,0 +1,156 @@
+package bitarray
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAtomic(t *testing.T) {
+	ng := max(4, runtime.GOMAXPROCS(0))
+
+	t.Run("set and clr", func(t *testing.T) {
+		// every goroutine sets its own bits, all of them sharing the same blocks
+		ba := New(64 * 4)
+		var wg sync.WaitGroup
+		for g := 0; g < ng; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for k := g; k < ba.n; k += ng {
+					ba.AtomicSet(k)
+				}
+			}(g)
+		}
+		wg.Wait()
+		if ba.Cnt() != ba.n {
+			t.Fatalf("Test AtomicSet failed. got = %d, exp = %d\n", ba.Cnt(), ba.n)
+		}
+
+		for g := 0; g < ng; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for k := g; k < ba.n; k += ng {
+					ba.AtomicClr(k)
+				}
+			}(g)
+		}
+		wg.Wait()
+		if ba.Cnt() != 0 {
+			t.Fatalf("Test AtomicClr failed. got = %d, exp = %d\n", ba.Cnt(), 0)
+		}
+	})
+
+	t.Run("tgl", func(t *testing.T) {
+		ba := New(64)
+		var wg sync.WaitGroup
+		for g := 0; g < ng; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 1000; i++ {
+					ba.AtomicTgl(i % ba.n)
+				}
+			}()
+		}
+		wg.Wait()
+		// the bits toggled an odd no. of times in total end up set
+		exp := 0
+		for k := 0; k < ba.n; k++ {
+			if ng*((1000-k+ba.n-1)/ba.n)%2 != 0 {
+				exp++
+			}
+		}
+		if ba.Cnt() != exp {
+			t.Fatalf("Test failed. got = %d, exp = %d\n", ba.Cnt(), exp)
+		}
+	})
+
+	t.Run("chkset", func(t *testing.T) {
+		// exactly one goroutine claims each bit
+		ba := New(1000)
+		var claimed atomic.Int64
+		var wg sync.WaitGroup
+		for g := 0; g < ng; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for k := 0; k < ba.n; k++ {
+					if !ba.AtomicChkSet(k) {
+						claimed.Add(1)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		if claimed.Load() != int64(ba.n) {
+			t.Fatalf("Test failed. got = %d, exp = %d\n", claimed.Load(), ba.n)
+		}
+
+		claimed.Store(0)
+		for g := 0; g < ng; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for k := 0; k < ba.n; k++ {
+					if ba.AtomicChkClr(k) {
+						claimed.Add(1)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		if claimed.Load() != int64(ba.n) || ba.Cnt() != 0 {
+			t.Fatalf("Test failed. got = %d, exp = %d\n", claimed.Load(), ba.n)
+		}
+	})
+
+	t.Run("cas", func(t *testing.T) {
+		ba := New(70)
+		if !ba.CompareAndSwapBit(65, Zero, One) || !ba.AtomicChk(65) {
+			t.Fatalf("Test failed. bit was not swapped\n")
+		}
+		if ba.CompareAndSwapBit(65, Zero, One) {
+			t.Fatalf("Test failed. bit was swapped from the wrong value\n")
+		}
+		if !ba.CompareAndSwapBit(65, One, One) || !ba.CompareAndSwapBit(65, One, Zero) || ba.AtomicChk(65) {
+			t.Fatalf("Test failed. bit was not swapped\n")
+		}
+		if ba.Cnt() != 0 {
+			t.Fatalf("Test failed. got = %d, exp = %d\n", ba.Cnt(), 0)
+		}
+
+		// only the lowest bit of the values is used
+		if !ba.CompareAndSwapBit(65, 2, 3) || !ba.AtomicChk(65) || ba.Cnt() != 1 {
+			t.Fatalf("Test failed. got = %s\n", ba.String())
+		}
+		if !ba.CompareAndSwapBit(65, 5, 2) || ba.Cnt() != 0 {
+			t.Fatalf("Test failed. got = %s\n", ba.String())
+		}
+	})
+}
+
+func BenchmarkAtomic(b *testing.B) {
+	b.Run("set, shared block", func(b *testing.B) {
+		ba := New(64)
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				ba.AtomicSet(i & 63)
+			}
+		})
+	})
+
+	b.Run("set, spread", func(b *testing.B) {
+		ba := New(1 << 16)
+		var g atomic.Int64
+		b.RunParallel(func(pb *testing.PB) {
+			// each goroutine works on its own cache lines
+			off := int(g.Add(1)) * 512 % ba.n
+			for i := 0; pb.Next(); i++ {
+				ba.AtomicSet(off + i&511)
+			}
+		})
+	})
+}
@@ -14,25 +14,29 @@ const (
 
 // BitArray is an array data structure that compactly stores bits.
 // Bits externally represented as `bool` are stored internally as `uint64`s.
-// The total number of bits stored is set at creation and is immutable.
+// The total number of bits stored is set at creation and only changes through
+// Append, AppendRange, Truncate and Resize.
+//
+// A BitArray must not be copied after creation, since the copy would share its
+// bits with the original; go vet reports such copies. Use Clone for a copy of
+// its own.
 type BitArray struct {
-	// buf is a backing array that bits writes into by default when the no. of bits requested to allocate is
-	// < 512. Only if more is asked, we'll skip buf and allocate directly into bits
-	buf  [8]Bit
+	_    noCopy
 	bits []Bit
 	n    int // no. of bits
 }
 
-// New creates a new BitArray of `n` bits. If n <= 512, no allocation is done.
+// New creates a new BitArray of `n` bits.
 func New(n int) (ba BitArray) {
-	nblk := nbitsToNblks(n)
 	ba.n = n
-	ba.bits = ba.buf[:]
-	if nblk <= 8 {
-		ba.bits = ba.buf[:nblk]
-		return
-	}
-	ba.bits = append(ba.bits, make([]Bit, nblk-len(ba.buf))...)
+	ba.bits = make([]Bit, nbitsToNblks(n))
+	return
+}
+
+// Clone returns a copy of ba that shares no storage with it.
+func (ba *BitArray) Clone() (c BitArray) {
+	c = New(ba.n)
+	copy(c.bits, ba.bits)
 	return
 }
 
@@ -48,43 +52,45 @@ func Copy(dst, src *BitArray) {
 			return
 		}
 
+		src.assertInvariants()
 		copy(dst.bits, src.bits)
 	}
 }
 
 // FromStr creates a BitArray from a bit string
-func FromStr(bs string) BitArray {
-	ba := New(len(bs))
+func FromStr(bs string) (ba BitArray) {
+	ba = New(len(bs))
 	for i, b := range bs {
 		if b == '1' {
 			ba.Set(i)
 		}
 	}
-	return ba
+	return
 }
 
 // FromUint64 creates a BitArray from the bit representation of u.
-func FromUint64(u uint64) BitArray {
-	ba := New(64)
+func FromUint64(u uint64) (ba BitArray) {
+	ba = New(64)
 	ba.bits[0] = u
-	return ba
+	return
 }
 
 // Size returns the no. of bits stored.
 func (ba *BitArray) Size() int { return ba.n }
 
 // Set sets the bit at position k.
-func (ba *BitArray) Set(k int) { bi, si := biandsi(k); set(&ba.bits[bi], si) }
+func (ba *BitArray) Set(k int) { ba.assertIdx(k); bi, si := biandsi(k); set(&ba.bits[bi], si) }
 
 // SetAll sets all the bits.
 func (ba *BitArray) SetAll() {
 	for i := range ba.bits {
 		ba.bits[i] = math.MaxUint64
 	}
+	ba.clrTail()
 }
 
 // Clr clears the bit at position k.
-func (ba *BitArray) Clr(k int) { bi, si := biandsi(k); clr(&ba.bits[bi], si) }
+func (ba *BitArray) Clr(k int) { ba.assertIdx(k); bi, si := biandsi(k); clr(&ba.bits[bi], si) }
 
 // ClrAll clears all the bits.
 func (ba *BitArray) ClrAll() {
@@ -95,6 +101,7 @@ func (ba *BitArray) ClrAll() {
 
 // ChkSet returns the value of the bit at position k before setting it.
 func (ba *BitArray) ChkSet(k int) (b bool) {
+	ba.assertIdx(k)
 	bi, si := biandsi(k)
 	u := &ba.bits[bi]
 	b = chk(*u, si) != 0
@@ -106,6 +113,7 @@ func (ba *BitArray) ChkSet(k int) (b bool) {
 
 // ChkClr returns the value of the bit at position k before clearing it.
 func (ba *BitArray) ChkClr(k int) (b bool) {
+	ba.assertIdx(k)
 	bi, si := biandsi(k)
 	u := &ba.bits[bi]
 	b = chk(*u, si) != 0
@@ -117,12 +125,14 @@ func (ba *BitArray) ChkClr(k int) (b bool) {
 
 // Tgl toggles the bit at position k.
 func (ba *BitArray) Tgl(k int) {
+	ba.assertIdx(k)
 	bi, si := biandsi(k)
 	ba.bits[bi] ^= 1 << si
 }
 
 // Cnt returns the number of set bits.
 func (ba *BitArray) Cnt() (n int) {
+	ba.assertInvariants()
 	for _, b := range ba.bits {
 		n += bits.OnesCount64(b)
 	}
@@ -131,18 +141,21 @@ func (ba *BitArray) Cnt() (n int) {
 
 // Chk returns the value of the bit at position k.
 func (ba *BitArray) Chk(k int) bool {
+	ba.assertIdx(k)
 	bi, si := biandsi(k)
 	return chk(ba.bits[bi], si) != 0
 }
 
 // Put sets the value of the bit at position k to v.
 func (ba *BitArray) Put(k int, v Bit) {
+	ba.assertIdx(k)
 	bi, si := biandsi(k)
 	put(&ba.bits[bi], si, v)
 }
 
 // Swap swaps the value of bit at position k with v. On return, v contains the old value.
 func (ba *BitArray) Swap(k int, b *Bit) {
+	ba.assertIdx(k)
 	bi, si := biandsi(k)
 	t := &ba.bits[bi]
 	ob := chk(*t, si)
@@ -164,6 +177,33 @@ func (ba *BitArray) String() string {
 	return string(sb)
 }
 
+// clrTail clears the unused bits of the last block beyond n.
+func (ba *BitArray) clrTail() {
+	if si := ba.n % 64; si != 0 {
+		ba.bits[len(ba.bits)-1] &= 1<<si - 1
+	}
+}
+
+// blk returns the i'th block with the unused bits beyond n masked off.
+// Blocks past the end of the array read as zero.
+func (ba *BitArray) blk(i int) Bit {
+	switch {
+	case i >= len(ba.bits):
+		return 0
+	case i == len(ba.bits)-1:
+		if si := ba.n % 64; si != 0 {
+			return ba.bits[i] & (1<<si - 1)
+		}
+	}
+	return ba.bits[i]
+}
+
+// noCopy lets go vet's copylocks check report copies of the struct it is embedded in.
+type noCopy struct{}
+
+func (*noCopy) Lock()   {}
+func (*noCopy) Unlock() {}
+
 func nbitsToNblks(n int) int { return int(math.Ceil(float64(n) / 64)) }
 
 func set(u *uint64, si uint64)        { *u |= 1 << si }
@@ -37,6 +37,27 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestClone(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for _, n := range []int{0, 4, 64, 512, 768} {
+		ba := New(n)
+		randomize(&ba, rng)
+		exp := ba.String()
+		c := ba.Clone()
+		if c.Size() != n || c.String() != exp {
+			t.Fatalf("Test %d failed. got = %s\nexp = %s\n", n, c.String(), exp)
+		}
+		if n == 0 {
+			continue
+		}
+		c.Tgl(0)
+		ba.Tgl(n - 1)
+		if c.Chk(0) == ba.Chk(0) || c.Chk(n-1) == ba.Chk(n-1) {
+			t.Fatalf("Test %d failed. the clone shares bits with the original\n", n)
+		}
+	}
+}
+
 func TestBitArray(t *testing.T) {
 	t.Run("swap", func(t *testing.T) {
 		ba := New(257)
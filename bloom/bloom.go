@@ -0,0 +1,302 @@
+// Package bloom provides Bloom filters backed by a bitarray.BitArray.
+//
+// A Bloom filter is a compact set that answers membership queries with no false
+// negatives and a tunable rate of false positives. Filter is the classic form, where
+// the bits of an item are spread over the whole array. BlockedFilter keeps all the bits
+// of an item within a single 512-bit block, so that Add and Test touch one cache line,
+// at the cost of a slightly higher false positive rate for the same size.
+package bloom
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/c2akula/bitarray"
+)
+
+// ErrIncompatible is returned when combining filters of different sizes or no. of hash functions.
+var ErrIncompatible = errors.New("bloom: incompatible filters")
+
+// blockBits is the no. of bits in a block of a BlockedFilter, the size of a cache line.
+const blockBits = 512
+
+// Filter is a Bloom filter.
+type Filter struct {
+	ba bitarray.BitArray
+	k  int // no. of hash functions
+}
+
+// New creates a Filter sized to hold n items with a false positive rate of p.
+// It panics if n is negative or p is not between 0 and 1, exclusive.
+func New(n int, p float64) *Filter {
+	m, k := Estimate(n, p)
+	return NewWithSize(m, k)
+}
+
+// NewWithSize creates a Filter of m bits that sets k bits per item.
+func NewWithSize(m, k int) *Filter {
+	return &Filter{ba: bitarray.New(max(m, 1)), k: max(k, 1)}
+}
+
+// Estimate returns the no. of bits m and the no. of hash functions k that hold n items
+// with a false positive rate of p. It panics if n is negative or p is not between 0 and 1,
+// exclusive.
+func Estimate(n int, p float64) (m, k int) {
+	if n < 0 {
+		panic("bloom: negative no. of items")
+	}
+	if !(p > 0 && p < 1) {
+		panic("bloom: false positive rate must be between 0 and 1, exclusive")
+	}
+	n = max(n, 1)
+	m = int(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	k = int(math.Round(float64(m) / float64(n) * math.Ln2))
+	return max(m, 1), max(k, 1)
+}
+
+// Size returns the no. of bits in the filter.
+func (f *Filter) Size() int { return f.ba.Size() }
+
+// K returns the no. of bits set per item.
+func (f *Filter) K() int { return f.k }
+
+// Add adds data to the filter.
+func (f *Filter) Add(data []byte) {
+	h1, h2 := hash(data)
+	m := uint64(f.ba.Size())
+	for i := 0; i < f.k; i++ {
+		f.ba.Set(int(h1 % m))
+		h1 += h2
+	}
+}
+
+// Test reports whether data may have been added to the filter.
+// A false result means it certainly has not been.
+func (f *Filter) Test(data []byte) bool {
+	h1, h2 := hash(data)
+	m := uint64(f.ba.Size())
+	for i := 0; i < f.k; i++ {
+		if !f.ba.Chk(int(h1 % m)) {
+			return false
+		}
+		h1 += h2
+	}
+	return true
+}
+
+// TestAndAdd adds data to the filter and reports whether it may have been added before.
+func (f *Filter) TestAndAdd(data []byte) bool {
+	h1, h2 := hash(data)
+	m := uint64(f.ba.Size())
+	found := true
+	for i := 0; i < f.k; i++ {
+		if !f.ba.ChkSet(int(h1 % m)) {
+			found = false
+		}
+		h1 += h2
+	}
+	return found
+}
+
+// Union adds the items of o to f. The filters must have the same size and no. of hash functions.
+func (f *Filter) Union(o *Filter) error {
+	if f.ba.Size() != o.ba.Size() || f.k != o.k {
+		return ErrIncompatible
+	}
+	f.ba.Or(&o.ba)
+	return nil
+}
+
+// Intersect keeps the bits of f that are also set in o, approximating the intersection of
+// the two sets. The filters must have the same size and no. of hash functions.
+func (f *Filter) Intersect(o *Filter) error {
+	if f.ba.Size() != o.ba.Size() || f.k != o.k {
+		return ErrIncompatible
+	}
+	f.ba.And(&o.ba)
+	return nil
+}
+
+// EstimatedCount returns an estimate of the no. of distinct items added to the filter,
+// derived from the no. of bits set.
+func (f *Filter) EstimatedCount() int { return estimate(f.ba.Size(), f.k, f.ba.Cnt()) }
+
+// ClrAll empties the filter.
+func (f *Filter) ClrAll() { f.ba.ClrAll() }
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface. The encoding is the
+// no. of hash functions as a uvarint followed by the binary encoding of the bits.
+func (f *Filter) MarshalBinary() ([]byte, error) { return marshal(&f.ba, f.k) }
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (f *Filter) UnmarshalBinary(data []byte) error {
+	k, err := unmarshal(&f.ba, data, 1)
+	if err == nil {
+		f.k = k
+	}
+	return err
+}
+
+// BlockedFilter is a Bloom filter that sets all the bits of an item within a single
+// 512-bit block.
+type BlockedFilter struct {
+	ba bitarray.BitArray
+	k  int // no. of hash functions
+}
+
+// NewBlocked creates a BlockedFilter sized to hold n items with a false positive rate of
+// about p. The size is rounded up to a whole no. of blocks. It panics if n is negative
+// or p is not between 0 and 1, exclusive.
+func NewBlocked(n int, p float64) *BlockedFilter {
+	m, k := Estimate(n, p)
+	return NewBlockedWithSize(m, k)
+}
+
+// NewBlockedWithSize creates a BlockedFilter of m bits, rounded up to a whole no. of
+// blocks, that sets k bits per item.
+func NewBlockedWithSize(m, k int) *BlockedFilter {
+	m = (max(m, 1) + blockBits - 1) / blockBits * blockBits
+	return &BlockedFilter{ba: bitarray.New(m), k: max(k, 1)}
+}
+
+// Size returns the no. of bits in the filter.
+func (f *BlockedFilter) Size() int { return f.ba.Size() }
+
+// K returns the no. of bits set per item.
+func (f *BlockedFilter) K() int { return f.k }
+
+// Add adds data to the filter.
+func (f *BlockedFilter) Add(data []byte) {
+	base, h1, h2 := f.locate(data)
+	for i := 0; i < f.k; i++ {
+		f.ba.Set(base + int(h1%blockBits))
+		h1 += h2
+	}
+}
+
+// Test reports whether data may have been added to the filter.
+// A false result means it certainly has not been.
+func (f *BlockedFilter) Test(data []byte) bool {
+	base, h1, h2 := f.locate(data)
+	for i := 0; i < f.k; i++ {
+		if !f.ba.Chk(base + int(h1%blockBits)) {
+			return false
+		}
+		h1 += h2
+	}
+	return true
+}
+
+// TestAndAdd adds data to the filter and reports whether it may have been added before.
+func (f *BlockedFilter) TestAndAdd(data []byte) bool {
+	base, h1, h2 := f.locate(data)
+	found := true
+	for i := 0; i < f.k; i++ {
+		if !f.ba.ChkSet(base + int(h1%blockBits)) {
+			found = false
+		}
+		h1 += h2
+	}
+	return found
+}
+
+// Union adds the items of o to f. The filters must have the same size and no. of hash functions.
+func (f *BlockedFilter) Union(o *BlockedFilter) error {
+	if f.ba.Size() != o.ba.Size() || f.k != o.k {
+		return ErrIncompatible
+	}
+	f.ba.Or(&o.ba)
+	return nil
+}
+
+// Intersect keeps the bits of f that are also set in o, approximating the intersection of
+// the two sets. The filters must have the same size and no. of hash functions.
+func (f *BlockedFilter) Intersect(o *BlockedFilter) error {
+	if f.ba.Size() != o.ba.Size() || f.k != o.k {
+		return ErrIncompatible
+	}
+	f.ba.And(&o.ba)
+	return nil
+}
+
+// EstimatedCount returns an estimate of the no. of distinct items added to the filter,
+// derived from the no. of bits set.
+func (f *BlockedFilter) EstimatedCount() int { return estimate(f.ba.Size(), f.k, f.ba.Cnt()) }
+
+// ClrAll empties the filter.
+func (f *BlockedFilter) ClrAll() { f.ba.ClrAll() }
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface. The encoding is the
+// no. of hash functions as a uvarint followed by the binary encoding of the bits.
+func (f *BlockedFilter) MarshalBinary() ([]byte, error) { return marshal(&f.ba, f.k) }
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (f *BlockedFilter) UnmarshalBinary(data []byte) error {
+	k, err := unmarshal(&f.ba, data, blockBits)
+	if err == nil {
+		f.k = k
+	}
+	return err
+}
+
+// locate returns the position of the block of data and the hashes that pick the bits within it.
+func (f *BlockedFilter) locate(data []byte) (base int, h1, h2 uint64) {
+	h0, h1 := hash(data)
+	h2 = mix(h1) | 1
+	return int(h0%uint64(f.ba.Size()/blockBits)) * blockBits, h1, h2
+}
+
+// estimate returns the estimated no. of items in a filter of m bits, k hash functions
+// and x bits set.
+func estimate(m, k, x int) int {
+	x = min(x, m-1)
+	return int(math.Round(-float64(m) / float64(k) * math.Log(1-float64(x)/float64(m))))
+}
+
+func marshal(ba *bitarray.BitArray, k int) ([]byte, error) {
+	return ba.AppendBinary(binary.AppendUvarint(nil, uint64(k)))
+}
+
+// unmarshal decodes the bits into ba and returns the no. of hash functions. The size of
+// the decoded array must be a positive multiple of unit.
+func unmarshal(ba *bitarray.BitArray, data []byte, unit int) (int, error) {
+	k, n := binary.Uvarint(data)
+	if n <= 0 || k == 0 || k > math.MaxInt32 {
+		return 0, errors.New("bloom: invalid encoding: malformed no. of hash functions")
+	}
+	var b bitarray.BitArray
+	if err := b.UnmarshalBinary(data[n:]); err != nil {
+		return 0, err
+	}
+	if b.Size() == 0 || b.Size()%unit != 0 {
+		return 0, fmt.Errorf("bloom: invalid encoding: size %d is not a positive multiple of %d", b.Size(), unit)
+	}
+	ba.Resize(b.Size())
+	bitarray.Copy(ba, &b)
+	return int(k), nil
+}
+
+// hash returns two independent 64-bit hashes of data for double hashing. The second
+// hash is odd. The hashes are stable across processes, so that filters can be persisted.
+func hash(data []byte) (uint64, uint64) {
+	// FNV-1a
+	h := uint64(14695981039346656037)
+	for _, c := range data {
+		h ^= uint64(c)
+		h *= 1099511628211
+	}
+	h1 := mix(h)
+	return h1, mix(h1) | 1
+}
+
+// mix is the finalizer of splitmix64.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,211 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+)
+
+// filter is the API shared by Filter and BlockedFilter.
+type filter interface {
+	Add([]byte)
+	Test([]byte) bool
+	TestAndAdd([]byte) bool
+	EstimatedCount() int
+	Size() int
+	K() int
+	MarshalBinary() ([]byte, error)
+	UnmarshalBinary([]byte) error
+}
+
+func key(i int) []byte { return binary.LittleEndian.AppendUint64(nil, uint64(i)) }
+
+func TestEstimate(t *testing.T) {
+	// the classic sizing: 1M items at 1% need ~9.59 bits per item and 7 hash functions
+	m, k := Estimate(1000000, 0.01)
+	if m != 9585059 || k != 7 {
+		t.Fatalf("Test failed. got = %d, %d, exp = %d, %d\n", m, k, 9585059, 7)
+	}
+}
+
+func TestEstimateInvalid(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		n    int
+		p    float64
+	}{
+		{"zero rate", 1000, 0},
+		{"negative rate", 1000, -0.1},
+		{"rate of one", 1000, 1},
+		{"rate above one", 1000, 1.5},
+		{"NaN rate", 1000, math.NaN()},
+		{"negative items", -1, 0.01},
+	} {
+		for name, fn := range map[string]func(){
+			"Estimate":   func() { Estimate(tt.n, tt.p) },
+			"New":        func() { New(tt.n, tt.p) },
+			"NewBlocked": func() { NewBlocked(tt.n, tt.p) },
+		} {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Fatalf("Test %s, %s failed. expected a panic\n", name, tt.name)
+					}
+				}()
+				fn()
+			}()
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	const n, p = 20000, 0.01
+
+	for _, tt := range []struct {
+		name string
+		new  func() filter
+		fp   float64 // tolerated false positive rate
+	}{
+		{"classic", func() filter { return New(n, p) }, 1.3 * p},
+		{"blocked", func() filter { return NewBlocked(n, p) }, 2 * p},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			f := tt.new()
+			for i := 0; i < n; i++ {
+				if f.TestAndAdd(key(i)) && i < 10 {
+					t.Fatalf("Test failed. %d reported as added before\n", i)
+				}
+			}
+			for i := 0; i < n; i++ {
+				if !f.Test(key(i)) {
+					t.Fatalf("Test failed. false negative for %d\n", i)
+				}
+				if !f.TestAndAdd(key(i)) {
+					t.Fatalf("Test failed. false negative for %d\n", i)
+				}
+			}
+
+			fps := 0
+			for i := n; i < 11*n; i++ {
+				if f.Test(key(i)) {
+					fps++
+				}
+			}
+			if rate := float64(fps) / (10 * n); rate > tt.fp {
+				t.Fatalf("Test failed. false positive rate = %f, exp <= %f\n", rate, tt.fp)
+			}
+
+			if c := f.EstimatedCount(); c < n*95/100 || c > n*105/100 {
+				t.Fatalf("Test failed. got = %d, exp ~ %d\n", c, n)
+			}
+
+			data, err := f.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			g := tt.new()
+			if err := g.UnmarshalBinary(data); err != nil {
+				t.Fatal(err)
+			}
+			if g.Size() != f.Size() || g.K() != f.K() || g.EstimatedCount() != f.EstimatedCount() {
+				t.Fatalf("Test failed. round-trip changed the filter\n")
+			}
+			for i := 0; i < n; i++ {
+				if !g.Test(key(i)) {
+					t.Fatalf("Test failed. false negative for %d after round-trip\n", i)
+				}
+			}
+
+			if err := g.UnmarshalBinary(data[:len(data)-1]); err == nil {
+				t.Fatalf("Test failed. expected an error for truncated data\n")
+			}
+			if err := g.UnmarshalBinary([]byte{0}); err == nil {
+				t.Fatalf("Test failed. expected an error for zero hash functions\n")
+			}
+		})
+	}
+}
+
+func TestSetOps(t *testing.T) {
+	a, b := New(1000, 0.01), New(1000, 0.01)
+	for i := 0; i < 500; i++ {
+		a.Add(key(i))
+	}
+	for i := 250; i < 750; i++ {
+		b.Add(key(i))
+	}
+
+	u := NewWithSize(a.Size(), a.K())
+	u.Union(a)
+	if err := u.Union(b); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 750; i++ {
+		if !u.Test(key(i)) {
+			t.Fatalf("Test Union failed. false negative for %d\n", i)
+		}
+	}
+
+	if err := a.Intersect(b); err != nil {
+		t.Fatal(err)
+	}
+	for i := 250; i < 500; i++ {
+		if !a.Test(key(i)) {
+			t.Fatalf("Test Intersect failed. false negative for %d\n", i)
+		}
+	}
+
+	if err := a.Union(New(2000, 0.01)); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("Test failed. got = %v, exp = %v\n", err, ErrIncompatible)
+	}
+
+	x, y := NewBlocked(1000, 0.01), NewBlocked(1000, 0.01)
+	x.Add(key(1))
+	y.Add(key(2))
+	if err := x.Union(y); err != nil || !x.Test(key(1)) || !x.Test(key(2)) {
+		t.Fatalf("Test blocked Union failed. got = %v\n", err)
+	}
+	if err := x.Intersect(NewBlockedWithSize(x.Size(), x.K()+1)); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("Test failed. got = %v, exp = %v\n", err, ErrIncompatible)
+	}
+}
+
+func BenchmarkFilter(b *testing.B) {
+	const n = 1 << 20
+	data := key(12345)
+
+	b.Run("classic add", func(b *testing.B) {
+		b.ReportAllocs()
+		f := New(n, 0.01)
+		for i := 0; i < b.N; i++ {
+			f.Add(data)
+		}
+	})
+
+	b.Run("classic test", func(b *testing.B) {
+		b.ReportAllocs()
+		f := New(n, 0.01)
+		f.Add(data)
+		for i := 0; i < b.N; i++ {
+			f.Test(data)
+		}
+	})
+
+	b.Run("blocked add", func(b *testing.B) {
+		b.ReportAllocs()
+		f := NewBlocked(n, 0.01)
+		for i := 0; i < b.N; i++ {
+			f.Add(data)
+		}
+	})
+
+	b.Run("blocked test", func(b *testing.B) {
+		b.ReportAllocs()
+		f := NewBlocked(n, 0.01)
+		f.Add(data)
+		for i := 0; i < b.N; i++ {
+			f.Test(data)
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package bitarray
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by the checked API.
+var (
+	// ErrOutOfRange is returned for a position or span that is not within the array.
+	ErrOutOfRange = errors.New("bitarray: index out of range")
+	// ErrSizeMismatch is returned when two arrays of different sizes are combined.
+	ErrSizeMismatch = errors.New("bitarray: size mismatch")
+)
+
+// The methods below are the checked counterparts of the ones without the Try prefix.
+// Instead of panicking or touching bits past the end of the array, they return an error
+// wrapping ErrOutOfRange or ErrSizeMismatch.
+
+// TrySet sets the bit at position k.
+func (ba *BitArray) TrySet(k int) error {
+	if err := ba.chkIdx(k); err != nil {
+		return err
+	}
+	ba.Set(k)
+	return nil
+}
+
+// TryClr clears the bit at position k.
+func (ba *BitArray) TryClr(k int) error {
+	if err := ba.chkIdx(k); err != nil {
+		return err
+	}
+	ba.Clr(k)
+	return nil
+}
+
+// TryTgl toggles the bit at position k.
+func (ba *BitArray) TryTgl(k int) error {
+	if err := ba.chkIdx(k); err != nil {
+		return err
+	}
+	ba.Tgl(k)
+	return nil
+}
+
+// TryPut sets the value of the bit at position k to v.
+func (ba *BitArray) TryPut(k int, v Bit) error {
+	if err := ba.chkIdx(k); err != nil {
+		return err
+	}
+	ba.Put(k, v&1)
+	return nil
+}
+
+// TryChk returns the value of the bit at position k.
+func (ba *BitArray) TryChk(k int) (bool, error) {
+	if err := ba.chkIdx(k); err != nil {
+		return false, err
+	}
+	return ba.Chk(k), nil
+}
+
+// TryChkSet returns the value of the bit at position k before setting it.
+func (ba *BitArray) TryChkSet(k int) (bool, error) {
+	if err := ba.chkIdx(k); err != nil {
+		return false, err
+	}
+	return ba.ChkSet(k), nil
+}
+
+// TryChkClr returns the value of the bit at position k before clearing it.
+func (ba *BitArray) TryChkClr(k int) (bool, error) {
+	if err := ba.chkIdx(k); err != nil {
+		return false, err
+	}
+	return ba.ChkClr(k), nil
+}
+
+// TryRange creates a Range object representing `n` bits starting at `b`.
+func (ba *BitArray) TryRange(b, n int) (Range, error) {
+	if !ba.inRange(b, n) {
+		return Range{}, fmt.Errorf("%w: %d bits at position %d of %d", ErrOutOfRange, n, b, ba.n)
+	}
+	return Range{ba, b, n}, nil
+}
+
+// TryCopy copies src into dst.
+func TryCopy(dst, src *BitArray) error {
+	if src != nil && src.n != dst.n {
+		return fmt.Errorf("%w: copy of %d bits into %d", ErrSizeMismatch, src.n, dst.n)
+	}
+	Copy(dst, src)
+	return nil
+}
+
+func (ba *BitArray) chkIdx(k int) error {
+	if uint(k) >= uint(ba.n) {
+		return fmt.Errorf("%w: position %d of %d", ErrOutOfRange, k, ba.n)
+	}
+	return nil
+}
+
+// inRange reports whether ba.Range(b, n) is valid. Like s[len(s):] for a slice, an empty
+// range may start at the end of the array.
+func (ba *BitArray) inRange(b, n int) bool { return inSpan(b, n, ba.n) }
+
+// inSpan reports whether the n bits starting at position b are all within size bits.
+func inSpan(b, n, size int) bool { return b >= 0 && n >= 0 && b <= size-n }
+
+// assertIdx panics if k is not a position in the array. It does nothing unless the
+// package is built with the bitarray_strict tag.
+func (ba *BitArray) assertIdx(k int) {
+	if strict && uint(k) >= uint(ba.n) {
+		panic("index out of bounds")
+	}
+}
+
+// assertInvariants panics if the blocks do not match the size or the unused bits of the
+// last block are set. It does nothing unless the package is built with the
+// bitarray_strict tag.
+func (ba *BitArray) assertInvariants() {
+	if !strict {
+		return
+	}
+	if len(ba.bits) != nbitsToNblks(ba.n) {
+		panic("no. of blocks does not match the size")
+	}
+	if si := ba.n % 64; si != 0 && ba.bits[len(ba.bits)-1]>>si != 0 {
+		panic("bits set past the end")
+	}
+}
@@ -0,0 +1,83 @@
+package bitarray
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestChecked(t *testing.T) {
+	ba := New(70)
+
+	t.Run("in range", func(t *testing.T) {
+		for _, k := range []int{0, 63, 64, 69} {
+			if err := ba.TrySet(k); err != nil {
+				t.Fatalf("Test TrySet(%d) failed. got = %v\n", k, err)
+			}
+			if v, err := ba.TryChk(k); !v || err != nil {
+				t.Fatalf("Test TryChk(%d) failed. got = %t, %v\n", k, v, err)
+			}
+			if err := ba.TryTgl(k); err != nil || ba.Chk(k) {
+				t.Fatalf("Test TryTgl(%d) failed. got = %v\n", k, err)
+			}
+			if err := ba.TryPut(k, One); err != nil || !ba.Chk(k) {
+				t.Fatalf("Test TryPut(%d) failed. got = %v\n", k, err)
+			}
+			if v, err := ba.TryChkClr(k); !v || err != nil || ba.Chk(k) {
+				t.Fatalf("Test TryChkClr(%d) failed. got = %t, %v\n", k, v, err)
+			}
+			if v, err := ba.TryChkSet(k); v || err != nil || !ba.Chk(k) {
+				t.Fatalf("Test TryChkSet(%d) failed. got = %t, %v\n", k, v, err)
+			}
+			if err := ba.TryClr(k); err != nil || ba.Chk(k) {
+				t.Fatalf("Test TryClr(%d) failed. got = %v\n", k, err)
+			}
+		}
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		// 70 to 127 are padding bits of the last block, and 128 is past it
+		for _, k := range []int{-1, 70, 127, 128, 1 << 40} {
+			errs := []error{ba.TrySet(k), ba.TryClr(k), ba.TryTgl(k), ba.TryPut(k, One)}
+			_, err := ba.TryChk(k)
+			errs = append(errs, err)
+			_, err = ba.TryChkSet(k)
+			errs = append(errs, err)
+			_, err = ba.TryChkClr(k)
+			errs = append(errs, err)
+			for _, err := range errs {
+				if !errors.Is(err, ErrOutOfRange) {
+					t.Fatalf("Test %d failed. got = %v, exp = %v\n", k, err, ErrOutOfRange)
+				}
+			}
+		}
+		if ba.Cnt() != 0 {
+			t.Fatalf("Test failed. got = %d bits set, exp = %d\n", ba.Cnt(), 0)
+		}
+	})
+
+	t.Run("range", func(t *testing.T) {
+		for _, tt := range []struct {
+			b, n int
+			ok   bool
+		}{{0, 70, true}, {69, 1, true}, {10, 0, true}, {-1, 2, false}, {5, -1, false}, {0, 71, false}, {70, 0, true}, {71, 0, false}, {70, 1, false}} {
+			r, err := ba.TryRange(tt.b, tt.n)
+			if tt.ok != (err == nil) || (!tt.ok && !errors.Is(err, ErrOutOfRange)) {
+				t.Fatalf("Test (%d, %d) failed. got = %v\n", tt.b, tt.n, err)
+			}
+			if tt.ok && (r.b != tt.b || r.n != tt.n) {
+				t.Fatalf("Test (%d, %d) failed. got = (%d, %d)\n", tt.b, tt.n, r.b, r.n)
+			}
+		}
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		src := FromStr("1011")
+		dst := New(4)
+		if err := TryCopy(&dst, &src); err != nil || dst.String() != "1011" {
+			t.Fatalf("Test failed. got = %s, %v\n", dst.String(), err)
+		}
+		if err := TryCopy(&ba, &src); !errors.Is(err, ErrSizeMismatch) {
+			t.Fatalf("Test failed. got = %v, exp = %v\n", err, ErrSizeMismatch)
+		}
+	})
+}
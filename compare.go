@@ -0,0 +1,106 @@
+package bitarray
+
+import "math/bits"
+
+// The functions below look only at the first Size() bits of their arguments, so that the
+// unused bits of the last block never affect the result. Arrays are ordered the way their
+// String forms are: by the first position at which they differ, a clear bit before a set
+// one, and an array before any longer one it is a prefix of.
+
+// Equal reports whether a and b have the same size and bits.
+func Equal(a, b *BitArray) bool { return a.n == b.n && !diff(a.bits, 0, a.n, b.bits, 0, b.n) }
+
+// Compare returns -1, 0 or +1 depending on whether a sorts before, the same as or after b.
+func Compare(a, b *BitArray) int { return compare(a.bits, 0, a.n, b.bits, 0, b.n) }
+
+// FirstDiff returns the first position at which a and b differ. If one is a prefix of the
+// other, that is the size of the shorter one. ok is false if they are equal.
+func FirstDiff(a, b *BitArray) (k int, ok bool) {
+	return found(firstdiff(a.bits, 0, a.n, b.bits, 0, b.n))
+}
+
+// Hash returns a hash of the size and bits of ba, mixed with seed. The hash is stable:
+// it is the same across processes and platforms, and for a Range holding the same bits.
+func (ba *BitArray) Hash(seed uint64) uint64 { return hash(seed, ba.bits, 0, ba.n) }
+
+// EqualRange reports whether a and b have the same size and bits.
+func EqualRange(a, b Range) bool {
+	return a.n == b.n && !diff(a.ba.bits, a.b, a.n, b.ba.bits, b.b, b.n)
+}
+
+// CompareRange returns -1, 0 or +1 depending on whether a sorts before, the same as or after b.
+func CompareRange(a, b Range) int { return compare(a.ba.bits, a.b, a.n, b.ba.bits, b.b, b.n) }
+
+// FirstDiffRange returns the first position, relative to the start of the ranges, at which
+// a and b differ. If one is a prefix of the other, that is the size of the shorter one.
+// ok is false if they are equal.
+func FirstDiffRange(a, b Range) (k int, ok bool) {
+	return found(firstdiff(a.ba.bits, a.b, a.n, b.ba.bits, b.b, b.n))
+}
+
+// Hash returns a hash of the size and bits of r, mixed with seed. It equals the hash of
+// a BitArray holding the same bits.
+func (r Range) Hash(seed uint64) uint64 { return hash(seed, r.ba.bits, r.b, r.n) }
+
+// diff reports whether the common prefix of the an bits of a starting at bit position
+// ak and the bn bits of b starting at bk differ.
+func diff(a []Bit, ak, an int, b []Bit, bk, bn int) bool {
+	n := min(an, bn)
+	for k := 0; k < n; k += 64 {
+		w := min(64, n-k)
+		if getbits(a, ak+k, w) != getbits(b, bk+k, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstdiff returns the first position at which the an bits of a starting at bit position
+// ak and the bn bits of b starting at bk differ, or a negative number.
+func firstdiff(a []Bit, ak, an int, b []Bit, bk, bn int) int {
+	n := min(an, bn)
+	for k := 0; k < n; k += 64 {
+		w := min(64, n-k)
+		if x := getbits(a, ak+k, w) ^ getbits(b, bk+k, w); x != 0 {
+			return k + bits.TrailingZeros64(x)
+		}
+	}
+	if an != bn {
+		return n
+	}
+	return -1
+}
+
+func compare(a []Bit, ak, an int, b []Bit, bk, bn int) int {
+	k := firstdiff(a, ak, an, b, bk, bn)
+	switch {
+	case k < 0:
+		return 0
+	case k == an:
+		return -1
+	case k == bn:
+		return 1
+	case getbits(a, ak+k, 1) == 0:
+		return -1
+	}
+	return 1
+}
+
+// hash hashes n and the n bits of s starting at bit position k, 64 at a time, each step
+// mixed with the finalizer of splitmix64.
+func hash(seed uint64, s []Bit, k, n int) uint64 {
+	h := mix(seed ^ (uint64(n)+1)*0x9e3779b97f4a7c15)
+	for j := 0; j < n; j += 64 {
+		h = mix(h ^ getbits(s, k+j, min(64, n-j)))
+	}
+	return h
+}
+
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
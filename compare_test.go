@@ -0,0 +1,153 @@
+package bitarray
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowFirstDiff is the reference FirstDiff, on the string forms.
+func slowFirstDiff(a, b string) (int, bool) {
+	for k := 0; k < min(len(a), len(b)); k++ {
+		if a[k] != b[k] {
+			return k, true
+		}
+	}
+	if len(a) != len(b) {
+		return min(len(a), len(b)), true
+	}
+	return -1, false
+}
+
+func TestCompare(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	t.Run("arrays", func(t *testing.T) {
+		for i := 0; i < 5000; i++ {
+			a := New(rng.Intn(200))
+			randomize(&a, rng)
+			// b is mostly a copy of a with a flipped bit, a changed size or both
+			bs := a.String()
+			if len(bs) != 0 && rng.Intn(4) != 0 {
+				k := rng.Intn(len(bs))
+				bs = bs[:k] + string('0'+'1'-bs[k]) + bs[k+1:]
+			}
+			switch rng.Intn(4) {
+			case 0:
+				bs = bs[:rng.Intn(len(bs)+1)]
+			case 1:
+				bs += strings.Repeat("0", rng.Intn(70))
+			}
+			b := FromStr(bs)
+			as := a.String()
+
+			if got, exp := Equal(&a, &b), as == bs; got != exp {
+				t.Fatalf("Test Equal failed. got = %t, exp = %t\na = %s\nb = %s\n", got, exp, as, bs)
+			}
+			if got, exp := Compare(&a, &b), strings.Compare(as, bs); got != exp {
+				t.Fatalf("Test Compare failed. got = %d, exp = %d\na = %s\nb = %s\n", got, exp, as, bs)
+			}
+			k, ok := FirstDiff(&a, &b)
+			if ek, eok := slowFirstDiff(as, bs); k != ek || ok != eok {
+				t.Fatalf("Test FirstDiff failed. got = %d, %t, exp = %d, %t\na = %s\nb = %s\n", k, ok, ek, eok, as, bs)
+			}
+			if as == bs && a.Hash(7) != b.Hash(7) {
+				t.Fatalf("Test Hash failed. equal arrays hash differently\na = %s\n", as)
+			}
+		}
+	})
+
+	t.Run("ranges", func(t *testing.T) {
+		a, b := New(300), New(300)
+		for i := 0; i < 5000; i++ {
+			randomize(&a, rng)
+			ab, bb := rng.Intn(150), rng.Intn(150)
+			an, bn := rng.Intn(150), rng.Intn(150)
+			if rng.Intn(2) == 0 {
+				// make the ranges share a prefix
+				bn = an
+				CopyRange(b.Range(bb, bn), a.Range(ab, an))
+				if an != 0 && rng.Intn(2) == 0 {
+					b.Tgl(bb + rng.Intn(an))
+				}
+			}
+			ra, rb := a.Range(ab, an), b.Range(bb, bn)
+			as, bs := ra.String(), rb.String()
+
+			if got, exp := EqualRange(ra, rb), as == bs; got != exp {
+				t.Fatalf("Test EqualRange failed. got = %t, exp = %t\na = %s\nb = %s\n", got, exp, as, bs)
+			}
+			if got, exp := CompareRange(ra, rb), strings.Compare(as, bs); got != exp {
+				t.Fatalf("Test CompareRange failed. got = %d, exp = %d\na = %s\nb = %s\n", got, exp, as, bs)
+			}
+			k, ok := FirstDiffRange(ra, rb)
+			if ek, eok := slowFirstDiff(as, bs); k != ek || ok != eok {
+				t.Fatalf("Test FirstDiffRange failed. got = %d, %t, exp = %d, %t\na = %s\nb = %s\n", k, ok, ek, eok, as, bs)
+			}
+			c := ra.Clone()
+			if ra.Hash(3) != c.Hash(3) || (as == bs && ra.Hash(3) != rb.Hash(3)) {
+				t.Fatalf("Test Hash failed. equal bits hash differently\na = %s\n", as)
+			}
+		}
+	})
+
+	t.Run("padding", func(t *testing.T) {
+		a, b := FromStr("10110"), FromStr("10110")
+		h := a.Hash(0)
+		b.bits[0] |= 1 << 40
+		if !Equal(&a, &b) || Compare(&a, &b) != 0 || b.Hash(0) != h {
+			t.Fatalf("Test failed. the padding bits were compared\n")
+		}
+		if _, ok := FirstDiff(&a, &b); ok {
+			t.Fatalf("Test failed. the padding bits were compared\n")
+		}
+	})
+
+	t.Run("hash", func(t *testing.T) {
+		// the hash is stable, so these must never change
+		for _, tt := range []struct {
+			s    string
+			seed uint64
+			exp  uint64
+		}{
+			{"", 0, 0xe220a8397b1dcdaf},
+			{"1", 0, 0x921b5c2e35c60d0},
+			{"10110", 0, 0x19902b560d804b40},
+			{"10110", 1, 0x5fe936d59b1e432c},
+			{strings.Repeat("01", 70), 0, 0x4338e5a860f2aa8b},
+		} {
+			ba := FromStr(tt.s)
+			if got := ba.Hash(tt.seed); got != tt.exp {
+				t.Fatalf("Test %q, %d failed. got = %#x, exp = %#x\n", tt.s, tt.seed, got, tt.exp)
+			}
+		}
+
+		// sizes take part in the hash, so trailing zeros make a difference
+		a, b := FromStr("101"), FromStr("1010")
+		if a.Hash(0) == b.Hash(0) {
+			t.Fatalf("Test failed. arrays of different sizes hash the same\n")
+		}
+	})
+}
+
+func BenchmarkCompare(b *testing.B) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	x := New(1 << 16)
+	randomize(&x, rng)
+	y := x.Clone()
+
+	b.Run("equal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			Equal(&x, &y)
+		}
+	})
+
+	b.Run("hash", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			x.Hash(0)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package bitarray
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// The binary encoding of a BitArray is the no. of bits as a uvarint followed by the bits
+// packed into ceil(n/8) bytes, least significant bit first, i.e. bit k is stored in byte
+// k/8 at bit k%8. The unused bits of the last byte are zero. The text encoding is the
+// string of '0's and '1's returned by String, and the JSON encoding is that string quoted.
+
+// AppendBinary implements the encoding.BinaryAppender interface.
+func (ba *BitArray) AppendBinary(b []byte) ([]byte, error) {
+	b = binary.AppendUvarint(b, uint64(ba.n))
+	nbytes := (ba.n + 7) / 8
+	for i := 0; i < nbytes; i++ {
+		b = append(b, byte(ba.blk(i/8)>>(8*(i%8))))
+	}
+	return b, nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+func (ba *BitArray) MarshalBinary() ([]byte, error) {
+	return ba.AppendBinary(make([]byte, 0, binary.MaxVarintLen64+(ba.n+7)/8))
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (ba *BitArray) UnmarshalBinary(data []byte) error {
+	u, k := binary.Uvarint(data)
+	if k <= 0 {
+		return errors.New("bitarray: invalid binary encoding: malformed size")
+	}
+	if u > math.MaxInt-7 {
+		return fmt.Errorf("bitarray: invalid binary encoding: size %d is too large", u)
+	}
+	n, data := int(u), data[k:]
+	if nbytes := (n + 7) / 8; len(data) != nbytes {
+		return fmt.Errorf("bitarray: invalid binary encoding: %d bits need %d bytes, got %d", n, nbytes, len(data))
+	}
+	if si := n % 8; si != 0 && data[len(data)-1]>>si != 0 {
+		return errors.New("bitarray: invalid binary encoding: unused bits of the last byte are set")
+	}
+
+	ba.Truncate(0)
+	ba.Resize(n)
+	for i, c := range data {
+		ba.bits[i/8] |= Bit(c) << (8 * (i % 8))
+	}
+	return nil
+}
+
+// GobEncode implements the gob.GobEncoder interface.
+func (ba *BitArray) GobEncode() ([]byte, error) { return ba.MarshalBinary() }
+
+// GobDecode implements the gob.GobDecoder interface.
+func (ba *BitArray) GobDecode(data []byte) error { return ba.UnmarshalBinary(data) }
+
+// AppendText implements the encoding.TextAppender interface.
+func (ba *BitArray) AppendText(b []byte) ([]byte, error) {
+	for i := 0; i < ba.n; i++ {
+		c := byte('0')
+		if ba.Chk(i) {
+			c = '1'
+		}
+		b = append(b, c)
+	}
+	return b, nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (ba *BitArray) MarshalText() ([]byte, error) {
+	return ba.AppendText(make([]byte, 0, ba.n))
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (ba *BitArray) UnmarshalText(text []byte) error {
+	for i, c := range text {
+		if c != '0' && c != '1' {
+			return fmt.Errorf("bitarray: invalid text encoding: unexpected %q at position %d", c, i)
+		}
+	}
+
+	ba.Truncate(0)
+	ba.Resize(len(text))
+	for i, c := range text {
+		if c == '1' {
+			ba.Set(i)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (ba *BitArray) MarshalJSON() ([]byte, error) {
+	b := make([]byte, 0, ba.n+2)
+	b = append(b, '"')
+	b, _ = ba.AppendText(b)
+	return append(b, '"'), nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (ba *BitArray) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("bitarray: invalid JSON encoding: %w", err)
+	}
+	return ba.UnmarshalText([]byte(s))
+}
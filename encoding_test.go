@@ -0,0 +1,135 @@
+package bitarray
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*BitArray)(nil)
+	_ encoding.BinaryUnmarshaler = (*BitArray)(nil)
+	_ encoding.BinaryAppender    = (*BitArray)(nil)
+	_ encoding.TextMarshaler     = (*BitArray)(nil)
+	_ encoding.TextUnmarshaler   = (*BitArray)(nil)
+	_ encoding.TextAppender      = (*BitArray)(nil)
+	_ json.Marshaler             = (*BitArray)(nil)
+	_ json.Unmarshaler           = (*BitArray)(nil)
+	_ gob.GobEncoder             = (*BitArray)(nil)
+	_ gob.GobDecoder             = (*BitArray)(nil)
+)
+
+func TestEncoding(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for _, n := range []int{0, 1, 7, 8, 9, 63, 64, 65, 257, 512, 1000} {
+		src := New(n)
+		randomize(&src, rng)
+
+		t.Run("binary", func(t *testing.T) {
+			data, err := src.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			dst := New(3)
+			dst.SetAll()
+			if err := dst.UnmarshalBinary(data); err != nil {
+				t.Fatal(err)
+			}
+			if dst.Size() != n || dst.String() != src.String() {
+				t.Fatalf("Test %d failed. got = %s\nexp = %s\n", n, dst.String(), src.String())
+			}
+		})
+
+		t.Run("text", func(t *testing.T) {
+			text, err := src.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(text) != src.String() {
+				t.Fatalf("Test %d failed. got = %s\nexp = %s\n", n, text, src.String())
+			}
+			var dst BitArray
+			if err := dst.UnmarshalText(text); err != nil {
+				t.Fatal(err)
+			}
+			if dst.String() != src.String() {
+				t.Fatalf("Test %d failed. got = %s\nexp = %s\n", n, dst.String(), src.String())
+			}
+		})
+
+		t.Run("json", func(t *testing.T) {
+			type rec struct {
+				Mask *BitArray
+			}
+			data, err := json.Marshal(rec{&src})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var r rec
+			if err := json.Unmarshal(data, &r); err != nil {
+				t.Fatal(err)
+			}
+			if r.Mask.String() != src.String() {
+				t.Fatalf("Test %d failed. got = %s\nexp = %s\n", n, r.Mask.String(), src.String())
+			}
+		})
+
+		t.Run("gob", func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := gob.NewEncoder(&buf).Encode(&src); err != nil {
+				t.Fatal(err)
+			}
+			var dst BitArray
+			if err := gob.NewDecoder(&buf).Decode(&dst); err != nil {
+				t.Fatal(err)
+			}
+			if dst.String() != src.String() {
+				t.Fatalf("Test %d failed. got = %s\nexp = %s\n", n, dst.String(), src.String())
+			}
+		})
+	}
+
+	t.Run("append", func(t *testing.T) {
+		ba := FromStr("1011")
+		b, _ := ba.AppendText([]byte("x="))
+		if string(b) != "x=1011" {
+			t.Fatalf("Test failed. got = %s, exp = %s\n", b, "x=1011")
+		}
+		b, _ = ba.AppendBinary([]byte{0xff})
+		if !bytes.Equal(b, []byte{0xff, 4, 0b1101}) {
+			t.Fatalf("Test failed. got = %x\n", b)
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		var ba BitArray
+		for _, data := range [][]byte{
+			{},
+			{0x80},
+			{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+			{9, 0xff},
+			{9, 0xff, 0x01, 0x00},
+			{4, 0x1f},
+			{0, 0x00},
+		} {
+			if err := ba.UnmarshalBinary(data); err == nil {
+				t.Fatalf("Test %x failed. expected an error\n", data)
+			}
+		}
+		for _, text := range []string{"0102", "1 0", "x"} {
+			if err := ba.UnmarshalText([]byte(text)); err == nil {
+				t.Fatalf("Test %q failed. expected an error\n", text)
+			}
+		}
+		for _, data := range []string{`"012"`, `12`, `"01`} {
+			if err := ba.UnmarshalJSON([]byte(data)); err == nil {
+				t.Fatalf("Test %s failed. expected an error\n", data)
+			}
+		}
+	})
+}
@@ -0,0 +1,242 @@
+package bitarray
+
+import (
+	"iter"
+	"math"
+	"math/bits"
+)
+
+// EWAH is a bitmap compressed with the Enhanced Word-Aligned Hybrid scheme. It is suited to
+// bitmaps made mostly of long runs of zeros (or ones).
+//
+// The blocks of the bitmap are stored as a sequence of marker words, each followed by a number
+// of literal blocks stored verbatim. A marker word describes a run of clean blocks, i.e. blocks
+// with all bits clear or all bits set, followed by its literal blocks:
+//
+//	bit  0      value of the bits in the clean blocks
+//	bits 1..32  no. of clean blocks
+//	bits 33..63 no. of literal blocks that follow the marker
+//
+// The operations on an EWAH work directly on the compressed words.
+type EWAH struct {
+	words []uint64
+	n     int // no. of bits
+	m     int // index of the last marker word
+}
+
+const (
+	ewahRunShift = 1
+	ewahLitShift = 33
+	ewahMaxRun   = 1<<32 - 1
+	ewahMaxLits  = 1<<31 - 1
+)
+
+func ewahRun(m uint64) int  { return int(m >> ewahRunShift & ewahMaxRun) }
+func ewahLits(m uint64) int { return int(m >> ewahLitShift) }
+
+// Compress returns the EWAH compressed form of ba.
+func Compress(ba *BitArray) *EWAH {
+	e := newEWAH(ba.n)
+	for i := range ba.bits {
+		e.add(ba.blk(i))
+	}
+	return e
+}
+
+func newEWAH(n int) *EWAH { return &EWAH{words: []uint64{0}, n: n} }
+
+// Decompress returns the bitmap as a BitArray.
+func (e *EWAH) Decompress() (ba BitArray) {
+	ba = New(e.n)
+	bi := 0
+	for i := 0; i < len(e.words); {
+		m := e.words[i]
+		r, l := ewahRun(m), ewahLits(m)
+		if m&1 != 0 {
+			for j := bi; j < bi+r; j++ {
+				ba.bits[j] = math.MaxUint64
+			}
+		}
+		bi += r
+		copy(ba.bits[bi:bi+l], e.words[i+1:i+1+l])
+		bi += l
+		i += 1 + l
+	}
+	return
+}
+
+// Size returns the no. of bits in the bitmap.
+func (e *EWAH) Size() int { return e.n }
+
+// Words returns the no. of 64-bit words used by the compressed bitmap.
+func (e *EWAH) Words() int { return len(e.words) }
+
+// Cnt returns the number of set bits.
+func (e *EWAH) Cnt() (n int) {
+	for i := 0; i < len(e.words); {
+		m := e.words[i]
+		r, l := ewahRun(m), ewahLits(m)
+		if m&1 != 0 {
+			n += 64 * r
+		}
+		for _, u := range e.words[i+1 : i+1+l] {
+			n += bits.OnesCount64(u)
+		}
+		i += 1 + l
+	}
+	return
+}
+
+// Ones returns an iterator over the positions of the set bits, in increasing order.
+func (e *EWAH) Ones() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		k := 0 // position of the first bit of the next block
+		for i := 0; i < len(e.words); {
+			m := e.words[i]
+			r, l := ewahRun(m), ewahLits(m)
+			if m&1 != 0 {
+				for j := k; j < min(k+64*r, e.n); j++ {
+					if !yield(j) {
+						return
+					}
+				}
+			}
+			k += 64 * r
+			for _, u := range e.words[i+1 : i+1+l] {
+				for ; u != 0; u &= u - 1 {
+					if !yield(k + bits.TrailingZeros64(u)) {
+						return
+					}
+				}
+				k += 64
+			}
+			i += 1 + l
+		}
+	}
+}
+
+// And returns the bitwise AND of e and o. Missing bits of a shorter operand are treated as zero.
+func (e *EWAH) And(o *EWAH) *EWAH { return ewahop(e, o, func(x, y uint64) uint64 { return x & y }) }
+
+// Or returns the bitwise OR of e and o. Missing bits of a shorter operand are treated as zero.
+func (e *EWAH) Or(o *EWAH) *EWAH { return ewahop(e, o, func(x, y uint64) uint64 { return x | y }) }
+
+// Xor returns the bitwise XOR of e and o. Missing bits of a shorter operand are treated as zero.
+func (e *EWAH) Xor(o *EWAH) *EWAH { return ewahop(e, o, func(x, y uint64) uint64 { return x ^ y }) }
+
+// AndNot returns the bits of e that are not set in o. Missing bits of a shorter operand are
+// treated as zero.
+func (e *EWAH) AndNot(o *EWAH) *EWAH {
+	return ewahop(e, o, func(x, y uint64) uint64 { return x &^ y })
+}
+
+// add appends the block u.
+func (e *EWAH) add(u uint64) {
+	switch u {
+	case 0:
+		e.addClean(0, 1)
+	case math.MaxUint64:
+		e.addClean(1, 1)
+	default:
+		e.addLiteral(u)
+	}
+}
+
+// addClean appends k clean blocks with all bits set to v.
+func (e *EWAH) addClean(v uint64, k int) {
+	for k > 0 {
+		m := e.words[e.m]
+		r := ewahRun(m)
+		if ewahLits(m) == 0 && (r == 0 || m&1 == v) && r < ewahMaxRun {
+			d := min(k, ewahMaxRun-r)
+			e.words[e.m] = uint64(r+d)<<ewahRunShift | v
+			k -= d
+			continue
+		}
+		e.words = append(e.words, 0)
+		e.m = len(e.words) - 1
+	}
+}
+
+// addLiteral appends the literal block u.
+func (e *EWAH) addLiteral(u uint64) {
+	if ewahLits(e.words[e.m]) == ewahMaxLits {
+		e.words = append(e.words, 0)
+		e.m = len(e.words) - 1
+	}
+	e.words[e.m] += 1 << ewahLitShift
+	e.words = append(e.words, u)
+}
+
+// ewahCursor walks the blocks of an EWAH, a run or a literal at a time.
+// Past the end, it reads as an endless run of clear blocks.
+type ewahCursor struct {
+	w    []uint64
+	i    int    // index of the next word
+	run  int    // no. of clean blocks left in the current run
+	bit  uint64 // value of the clean blocks, 0 or all ones
+	lits int    // no. of literal blocks left after the current run
+}
+
+func (c *ewahCursor) load() {
+	for c.run == 0 && c.lits == 0 {
+		if c.i >= len(c.w) {
+			c.run, c.bit = math.MaxInt, 0
+			return
+		}
+		m := c.w[c.i]
+		c.i++
+		c.run, c.lits, c.bit = ewahRun(m), ewahLits(m), -(m & 1)
+	}
+}
+
+// ewahop combines a and b block by block with op, skipping whole runs where possible.
+func ewahop(a, b *EWAH, op func(x, y uint64) uint64) *EWAH {
+	e := newEWAH(max(a.n, b.n))
+	ca, cb := ewahCursor{w: a.words}, ewahCursor{w: b.words}
+	for nb := nbitsToNblks(e.n); nb > 0; {
+		ca.load()
+		cb.load()
+		var k int
+		switch {
+		case ca.run > 0 && cb.run > 0:
+			k = min(min(ca.run, cb.run), nb)
+			e.addClean(op(ca.bit, cb.bit)&1, k)
+			ca.run -= k
+			cb.run -= k
+		case ca.run > 0:
+			k = min(min(ca.run, cb.lits), nb)
+			runlit(e, ca.bit, &cb, k, op)
+			ca.run -= k
+		case cb.run > 0:
+			k = min(min(cb.run, ca.lits), nb)
+			runlit(e, cb.bit, &ca, k, func(x, y uint64) uint64 { return op(y, x) })
+			cb.run -= k
+		default:
+			k = min(min(ca.lits, cb.lits), nb)
+			for j := 0; j < k; j++ {
+				e.add(op(ca.w[ca.i+j], cb.w[cb.i+j]))
+			}
+			ca.i += k
+			ca.lits -= k
+			cb.i += k
+			cb.lits -= k
+		}
+		nb -= k
+	}
+	return e
+}
+
+// runlit combines k clean blocks of value v with the next k literal blocks of c.
+func runlit(e *EWAH, v uint64, c *ewahCursor, k int, op func(x, y uint64) uint64) {
+	// the literals do not matter when the clean blocks decide the result on their own
+	if r := op(v, 0); r == op(v, math.MaxUint64) && (r == 0 || r == math.MaxUint64) {
+		e.addClean(r&1, k)
+	} else {
+		for _, u := range c.w[c.i : c.i+k] {
+			e.add(op(v, u))
+		}
+	}
+	c.i += k
+	c.lits -= k
+}
@@ -0,0 +1,127 @@
+package bitarray
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// sparse returns a BitArray of n bits made of random runs of zeros and ones,
+// with literal blocks in between.
+func sparse(n int, rng *rand.Rand) (ba BitArray) {
+	ba = New(n)
+	for k := 0; k < n; {
+		r := rng.Intn(1000)
+		switch rng.Intn(4) {
+		case 0:
+			for j := k; j < min(k+r, n); j++ {
+				ba.Set(j)
+			}
+		case 1:
+			for j := k; j < min(k+r, n); j++ {
+				if rng.Intn(2) == 0 {
+					ba.Set(j)
+				}
+			}
+		}
+		k += r
+	}
+	return
+}
+
+func TestEWAH(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	t.Run("compress", func(t *testing.T) {
+		for _, n := range []int{0, 1, 63, 64, 65, 1000, 10000, 100003} {
+			ba := sparse(n, rng)
+			e := Compress(&ba)
+			d := e.Decompress()
+			if e.Size() != n || d.String() != ba.String() {
+				t.Fatalf("Test %d failed. got = %s\nexp = %s\n", n, d.String(), ba.String())
+			}
+			if e.Cnt() != ba.Cnt() {
+				t.Fatalf("Test %d failed. got = %d, exp = %d\n", n, e.Cnt(), ba.Cnt())
+			}
+
+			var ones []int
+			for k := range e.Ones() {
+				ones = append(ones, k)
+			}
+			j := 0
+			for k := range ba.Ones() {
+				if j >= len(ones) || ones[j] != k {
+					t.Fatalf("Test %d failed. position %d missing from Ones\n", n, k)
+				}
+				j++
+			}
+			if j != len(ones) {
+				t.Fatalf("Test %d failed. got = %d, exp = %d\n", n, len(ones), j)
+			}
+		}
+	})
+
+	t.Run("runs", func(t *testing.T) {
+		ba := New(1 << 20)
+		ba.Set(5)
+		ba.Set(1<<20 - 1)
+		e := Compress(&ba)
+		if e.Words() != 4 {
+			t.Fatalf("Test failed. got = %d words, exp = %d\n", e.Words(), 4)
+		}
+	})
+
+	ops := []struct {
+		name string
+		fn   func(a, b *EWAH) *EWAH
+		ref  func(dst, a, b *BitArray)
+	}{
+		{"and", (*EWAH).And, And},
+		{"or", (*EWAH).Or, Or},
+		{"xor", (*EWAH).Xor, Xor},
+		{"andnot", (*EWAH).AndNot, AndNot},
+	}
+
+	for _, op := range ops {
+		t.Run(op.name, func(t *testing.T) {
+			for i := 0; i < 50; i++ {
+				a, b := sparse(rng.Intn(20000), rng), sparse(rng.Intn(20000), rng)
+				dst := New(max(a.n, b.n))
+				op.ref(&dst, &a, &b)
+
+				ea, eb := Compress(&a), Compress(&b)
+				e := op.fn(ea, eb)
+				if d := e.Decompress(); d.String() != dst.String() {
+					t.Fatalf("Test failed. got = %s\nexp = %s\n", d.String(), dst.String())
+				}
+				if e.Cnt() != dst.Cnt() {
+					t.Fatalf("Test failed. got = %d, exp = %d\n", e.Cnt(), dst.Cnt())
+				}
+				// the result must be as compact as compressing the uncompressed result
+				if exp := Compress(&dst).Words(); e.Words() != exp {
+					t.Fatalf("Test failed. got = %d words, exp = %d\n", e.Words(), exp)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkEWAH(b *testing.B) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	x, y := sparse(1<<20, rng), sparse(1<<20, rng)
+	ex, ey := Compress(&x), Compress(&y)
+
+	b.Run("and", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ex.And(ey)
+		}
+	})
+
+	b.Run("cnt", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ex.Cnt()
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package bitarray
+
+// SetRange sets the n bits starting at position b.
+func (ba *BitArray) SetRange(b, n int) {
+	ba.chkSpan(b, n)
+	fillbits(ba.bits, b, n, One)
+}
+
+// ClrRange clears the n bits starting at position b.
+func (ba *BitArray) ClrRange(b, n int) {
+	ba.chkSpan(b, n)
+	fillbits(ba.bits, b, n, Zero)
+}
+
+// TglRange toggles the n bits starting at position b.
+func (ba *BitArray) TglRange(b, n int) {
+	ba.chkSpan(b, n)
+	tglbits(ba.bits, b, n)
+}
+
+// PutRange sets the n bits starting at position b to v.
+func (ba *BitArray) PutRange(b, n int, v Bit) {
+	ba.chkSpan(b, n)
+	fillbits(ba.bits, b, n, v)
+}
+
+// Fill sets all the bits in the range to v.
+func (r Range) Fill(v Bit) { fillbits(r.ba.bits, r.b, r.n, v) }
+
+// chkSpan panics if the n bits starting at position b are not all within the array.
+func (ba *BitArray) chkSpan(b, n int) {
+	if !inSpan(b, n, ba.n) {
+		panic("index out of bounds")
+	}
+}
@@ -0,0 +1,74 @@
+package bitarray
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFillRange(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	ba := New(300)
+
+	for _, tt := range []struct {
+		name string
+		fn   func(b, n int)
+		bit  func(c byte) byte
+	}{
+		{"set", ba.SetRange, func(byte) byte { return '1' }},
+		{"clr", ba.ClrRange, func(byte) byte { return '0' }},
+		{"tgl", ba.TglRange, func(c byte) byte { return c ^ 1 }},
+		{"put 1", func(b, n int) { ba.PutRange(b, n, One) }, func(byte) byte { return '1' }},
+		{"put 0", func(b, n int) { ba.PutRange(b, n, Zero) }, func(byte) byte { return '0' }},
+		{"fill", func(b, n int) { ba.Range(b, n).Fill(One) }, func(byte) byte { return '1' }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			for b := 0; b < 140; b++ {
+				for n := 0; b+n <= ba.n; n += 1 + n/8 {
+					randomize(&ba, rng)
+					exp := []byte(ba.String())
+					for k := b; k < b+n; k++ {
+						exp[k] = tt.bit(exp[k])
+					}
+					tt.fn(b, n)
+					if got := ba.String(); got != string(exp) {
+						t.Fatalf("Test (%d, %d) failed. got = %s\nexp = %s\n", b, n, got, exp)
+					}
+					if got := ba.Cnt(); got != strings.Count(string(exp), "1") {
+						t.Fatalf("Test (%d, %d) failed. the bits past the end changed\n", b, n)
+					}
+				}
+			}
+		})
+	}
+
+	t.Run("bounds", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("Test failed. expected a panic\n")
+			}
+		}()
+		ba.SetRange(250, 51)
+	})
+}
+
+func BenchmarkFillRange(b *testing.B) {
+	ba := New(10000)
+
+	b.Run("set", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for k := 100; k < ba.n; k++ {
+				ba.Set(k)
+			}
+		}
+	})
+
+	b.Run("set-range", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ba.SetRange(100, ba.n-100)
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package bitarray
+
+// Append appends the bit b, growing the array by one bit.
+func (ba *BitArray) Append(b Bit) {
+	ba.Resize(ba.n + 1)
+	bi, si := biandsi(ba.n - 1)
+	ba.bits[bi] |= (b & 1) << si
+}
+
+// AppendRange appends the bits of r, growing the array by r's no. of bits.
+// r may be a range over ba itself.
+func (ba *BitArray) AppendRange(r Range) {
+	k := ba.n
+	ba.Resize(k + r.n)
+	movebits(ba.bits, k, r.ba.bits, r.b, r.n)
+}
+
+// Grow grows the capacity of the array, if necessary, to guarantee space for another n bits.
+// After Grow(n), at least n bits can be appended without another allocation.
+// It does not change the no. of bits stored.
+func (ba *BitArray) Grow(n int) {
+	if n < 0 {
+		panic("cannot be negative")
+	}
+	nblk := nbitsToNblks(ba.n + n)
+	if nblk <= cap(ba.bits) {
+		return
+	}
+	// grow geometrically, so that a sequence of appends is amortized O(1)
+	bits := make([]Bit, len(ba.bits), max(nblk, 2*cap(ba.bits)))
+	copy(bits, ba.bits)
+	ba.bits = bits
+}
+
+// Truncate discards all but the first n bits.
+// It panics if n is negative or greater than the no. of bits stored.
+func (ba *BitArray) Truncate(n int) {
+	if n < 0 || n > ba.n {
+		panic("truncation out of range")
+	}
+	ba.n = n
+	ba.bits = ba.bits[:nbitsToNblks(n)]
+	ba.clrTail()
+}
+
+// Resize changes the no. of bits stored to n. If n is larger than the current size,
+// the new bits are cleared, otherwise the array is truncated to n bits.
+func (ba *BitArray) Resize(n int) {
+	if n <= ba.n {
+		ba.Truncate(n)
+		return
+	}
+
+	ba.Grow(n - ba.n)
+	// the unused bits of the last block and the blocks past it may hold stale bits
+	ba.clrTail()
+	nblk := len(ba.bits)
+	ba.bits = ba.bits[:nbitsToNblks(n)]
+	clear(ba.bits[nblk:])
+	ba.n = n
+}
+
+// InsertBits inserts n cleared bits at position pos, shifting the bits at and after pos
+// towards higher positions.
+func (ba *BitArray) InsertBits(pos, n int) {
+	if pos < 0 || pos > ba.n || n < 0 {
+		panic("index out of bounds")
+	}
+	k := ba.n
+	ba.Resize(k + n)
+	movebits(ba.bits, pos+n, ba.bits, pos, k-pos)
+	fillbits(ba.bits, pos, n, Zero)
+}
+
+// InsertRange inserts the bits of r at position pos, shifting the bits at and after pos
+// towards higher positions. r may be a range over ba itself.
+func (ba *BitArray) InsertRange(pos int, r Range) {
+	s, sk := r.ba.bits, r.b
+	if r.ba == ba {
+		// the bits of r may move with the insertion, so take a copy first
+		s, sk = make([]Bit, nbitsToNblks(r.n)), 0
+		movebits(s, 0, r.ba.bits, r.b, r.n)
+	}
+	ba.InsertBits(pos, r.n)
+	movebits(ba.bits, pos, s, sk, r.n)
+}
+
+// DeleteBits removes n bits starting at position pos, shifting the bits after them
+// towards lower positions.
+func (ba *BitArray) DeleteBits(pos, n int) {
+	if pos < 0 || n < 0 || pos+n > ba.n {
+		panic("index out of bounds")
+	}
+	movebits(ba.bits, pos, ba.bits, pos+n, ba.n-pos-n)
+	ba.Truncate(ba.n - n)
+}
@@ -0,0 +1,153 @@
+package bitarray
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGrow(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	t.Run("append", func(t *testing.T) {
+		var ba BitArray
+		var sb strings.Builder
+		for i := 0; i < 1000; i++ {
+			b := Bit(rng.Intn(2))
+			ba.Append(b)
+			sb.WriteByte('0' + byte(b))
+			if ba.Size() != i+1 {
+				t.Fatalf("Test failed. got = %d, exp = %d\n", ba.Size(), i+1)
+			}
+		}
+		if ba.String() != sb.String() {
+			t.Fatalf("Test failed. got = %s\nexp = %s\n", ba.String(), sb.String())
+		}
+	})
+
+	t.Run("append past inline storage", func(t *testing.T) {
+		ba := New(500)
+		ba.SetAll()
+		for i := 0; i < 100; i++ {
+			ba.Append(Zero)
+		}
+		exp := strings.Repeat("1", 500) + strings.Repeat("0", 100)
+		if ba.String() != exp {
+			t.Fatalf("Test failed. got = %s\nexp = %s\n", ba.String(), exp)
+		}
+	})
+
+	t.Run("append-range", func(t *testing.T) {
+		src := New(300)
+		randomize(&src, rng)
+		ba := FromStr("101")
+		ba.AppendRange(src.Range(7, 200))
+		exp := "101" + src.String()[7:207]
+		if ba.String() != exp {
+			t.Fatalf("Test failed. got = %s\nexp = %s\n", ba.String(), exp)
+		}
+
+		// a range over the array itself
+		ba.AppendRange(ba.Range(1, 150))
+		exp += exp[1:151]
+		if ba.String() != exp {
+			t.Fatalf("Test failed. got = %s\nexp = %s\n", ba.String(), exp)
+		}
+	})
+
+	t.Run("truncate and resize", func(t *testing.T) {
+		for _, n := range []int{10, 64, 200, 512, 900} {
+			ba := New(n)
+			ba.SetAll()
+			for _, m := range []int{0, 1, 63, 65, 300} {
+				if m > n {
+					continue
+				}
+				ba.Truncate(m)
+				if ba.Size() != m || ba.Cnt() != m {
+					t.Fatalf("Test Truncate(%d) of %d bits failed. got = %d bits, %d set\n", m, n, ba.Size(), ba.Cnt())
+				}
+				// the bits exposed again by Resize must be zero
+				ba.Resize(n)
+				exp := strings.Repeat("1", m) + strings.Repeat("0", n-m)
+				if ba.String() != exp {
+					t.Fatalf("Test Resize(%d) failed. got = %s\nexp = %s\n", n, ba.String(), exp)
+				}
+				if ba.Cnt() != m {
+					t.Fatalf("Test Resize(%d) failed. got = %d, exp = %d\n", n, ba.Cnt(), m)
+				}
+				ba.SetAll()
+			}
+		}
+	})
+
+	t.Run("grow", func(t *testing.T) {
+		ba := New(10)
+		ba.Grow(1000)
+		if ba.Size() != 10 {
+			t.Fatalf("Test failed. got = %d, exp = %d\n", ba.Size(), 10)
+		}
+		bits := &ba.bits[:cap(ba.bits)][0]
+		for i := 0; i < 1000; i++ {
+			ba.Append(One)
+		}
+		if &ba.bits[0] != bits {
+			t.Fatalf("Test failed. Append reallocated after Grow\n")
+		}
+	})
+}
+
+func TestInsertDelete(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for i := 0; i < 500; i++ {
+		ba := New(rng.Intn(400))
+		randomize(&ba, rng)
+		s := ba.String()
+		pos, n := rng.Intn(ba.n+1), rng.Intn(200)
+
+		ba.InsertBits(pos, n)
+		exp := s[:pos] + strings.Repeat("0", n) + s[pos:]
+		if ba.String() != exp {
+			t.Fatalf("Test InsertBits(%d, %d) failed. got = %s\nexp = %s\n", pos, n, ba.String(), exp)
+		}
+
+		ba.DeleteBits(pos, n)
+		if ba.String() != s {
+			t.Fatalf("Test DeleteBits(%d, %d) failed. got = %s\nexp = %s\n", pos, n, ba.String(), s)
+		}
+
+		src := New(1 + rng.Intn(300))
+		randomize(&src, rng)
+		b := rng.Intn(src.n)
+		m := rng.Intn(src.n - b + 1)
+		ba.InsertRange(pos, src.Range(b, m))
+		exp = s[:pos] + src.String()[b:b+m] + s[pos:]
+		if ba.String() != exp {
+			t.Fatalf("Test InsertRange(%d, (%d, %d)) failed. got = %s\nexp = %s\n", pos, b, m, ba.String(), exp)
+		}
+		if ba.Cnt() != strings.Count(exp, "1") {
+			t.Fatalf("Test InsertRange(%d, (%d, %d)) failed. garbage in unused bits\n", pos, b, m)
+		}
+	}
+
+	t.Run("insert-range from itself", func(t *testing.T) {
+		ba := New(300)
+		randomize(&ba, rng)
+		s := ba.String()
+		ba.InsertRange(10, ba.Range(5, 200))
+		exp := s[:10] + s[5:205] + s[10:]
+		if ba.String() != exp {
+			t.Fatalf("Test failed. got = %s\nexp = %s\n", ba.String(), exp)
+		}
+	})
+}
+
+func BenchmarkAppend(b *testing.B) {
+	b.ReportAllocs()
+	var ba BitArray
+	for i := 0; i < b.N; i++ {
+		ba.Append(Bit(i & 1))
+	}
+}
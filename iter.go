@@ -0,0 +1,199 @@
+package bitarray
+
+import (
+	"iter"
+	"math/bits"
+)
+
+// NextSet returns the position of the first set bit at or after `from`.
+// ok is false if there is none.
+func (ba *BitArray) NextSet(from int) (k int, ok bool) {
+	return found(nextset(ba.bits, max(from, 0), ba.n))
+}
+
+// NextClr returns the position of the first clear bit at or after `from`.
+// ok is false if there is none.
+func (ba *BitArray) NextClr(from int) (k int, ok bool) {
+	return found(nextclr(ba.bits, max(from, 0), ba.n))
+}
+
+// PrevSet returns the position of the last set bit at or before `from`.
+// ok is false if there is none.
+func (ba *BitArray) PrevSet(from int) (k int, ok bool) {
+	return found(prevset(ba.bits, 0, min(from, ba.n-1)))
+}
+
+// PrevClr returns the position of the last clear bit at or before `from`.
+// ok is false if there is none.
+func (ba *BitArray) PrevClr(from int) (k int, ok bool) {
+	return found(prevclr(ba.bits, 0, min(from, ba.n-1)))
+}
+
+// All returns an iterator over the positions and values of all the bits.
+func (ba *BitArray) All() iter.Seq2[int, bool] { return all(ba.bits, 0, ba.n) }
+
+// Ones returns an iterator over the positions of the set bits, in increasing order.
+func (ba *BitArray) Ones() iter.Seq[int] { return ones(ba.bits, 0, ba.n) }
+
+// Zeros returns an iterator over the positions of the clear bits, in increasing order.
+func (ba *BitArray) Zeros() iter.Seq[int] { return zeros(ba.bits, 0, ba.n) }
+
+// NextSet returns the position, relative to the start of the range, of the first set bit
+// at or after `from`. ok is false if there is none.
+func (r Range) NextSet(from int) (k int, ok bool) {
+	return found(nextset(r.ba.bits, r.b+min(max(from, 0), r.n), r.b+r.n) - r.b)
+}
+
+// NextClr returns the position, relative to the start of the range, of the first clear bit
+// at or after `from`. ok is false if there is none.
+func (r Range) NextClr(from int) (k int, ok bool) {
+	return found(nextclr(r.ba.bits, r.b+min(max(from, 0), r.n), r.b+r.n) - r.b)
+}
+
+// PrevSet returns the position, relative to the start of the range, of the last set bit
+// at or before `from`. ok is false if there is none.
+func (r Range) PrevSet(from int) (k int, ok bool) {
+	return found(prevset(r.ba.bits, r.b, r.b+min(from, r.n-1)) - r.b)
+}
+
+// PrevClr returns the position, relative to the start of the range, of the last clear bit
+// at or before `from`. ok is false if there is none.
+func (r Range) PrevClr(from int) (k int, ok bool) {
+	return found(prevclr(r.ba.bits, r.b, r.b+min(from, r.n-1)) - r.b)
+}
+
+// All returns an iterator over the positions, relative to the start of the range, and values
+// of the bits in the range.
+func (r Range) All() iter.Seq2[int, bool] { return all(r.ba.bits, r.b, r.b+r.n) }
+
+// Ones returns an iterator over the positions, relative to the start of the range, of the set bits.
+func (r Range) Ones() iter.Seq[int] { return ones(r.ba.bits, r.b, r.b+r.n) }
+
+// Zeros returns an iterator over the positions, relative to the start of the range, of the clear bits.
+func (r Range) Zeros() iter.Seq[int] { return zeros(r.ba.bits, r.b, r.b+r.n) }
+
+func found(k int) (int, bool) {
+	if k < 0 {
+		return -1, false
+	}
+	return k, true
+}
+
+// nextset returns the position of the first set bit in [from, hi) or a negative number.
+func nextset(s []Bit, from, hi int) int {
+	if from >= hi {
+		return -1
+	}
+	bi, si := biandsi(from)
+	u := s[bi] >> si << si
+	for {
+		if u != 0 {
+			if k := int(bi)*64 + bits.TrailingZeros64(u); k < hi {
+				return k
+			}
+			return -1
+		}
+		bi++
+		if int(bi)*64 >= hi {
+			return -1
+		}
+		u = s[bi]
+	}
+}
+
+// nextclr returns the position of the first clear bit in [from, hi) or a negative number.
+func nextclr(s []Bit, from, hi int) int {
+	if from >= hi {
+		return -1
+	}
+	bi, si := biandsi(from)
+	u := ^s[bi] >> si << si
+	for {
+		if u != 0 {
+			if k := int(bi)*64 + bits.TrailingZeros64(u); k < hi {
+				return k
+			}
+			return -1
+		}
+		bi++
+		if int(bi)*64 >= hi {
+			return -1
+		}
+		u = ^s[bi]
+	}
+}
+
+// prevset returns the position of the last set bit in [lo, from] or a negative number.
+func prevset(s []Bit, lo, from int) int {
+	if from < lo {
+		return -1
+	}
+	bi, si := biandsi(from)
+	u := s[bi] & (2<<si - 1)
+	for {
+		if u != 0 {
+			if k := int(bi)*64 + 63 - bits.LeadingZeros64(u); k >= lo {
+				return k
+			}
+			return -1
+		}
+		if int(bi)*64 <= lo {
+			return -1
+		}
+		bi--
+		u = s[bi]
+	}
+}
+
+// prevclr returns the position of the last clear bit in [lo, from] or a negative number.
+func prevclr(s []Bit, lo, from int) int {
+	if from < lo {
+		return -1
+	}
+	bi, si := biandsi(from)
+	u := ^s[bi] & (2<<si - 1)
+	for {
+		if u != 0 {
+			if k := int(bi)*64 + 63 - bits.LeadingZeros64(u); k >= lo {
+				return k
+			}
+			return -1
+		}
+		if int(bi)*64 <= lo {
+			return -1
+		}
+		bi--
+		u = ^s[bi]
+	}
+}
+
+func all(s []Bit, lo, hi int) iter.Seq2[int, bool] {
+	return func(yield func(int, bool) bool) {
+		for k := lo; k < hi; k++ {
+			bi, si := biandsi(k)
+			if !yield(k-lo, chk(s[bi], si) != 0) {
+				return
+			}
+		}
+	}
+}
+
+func ones(s []Bit, lo, hi int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for k := nextset(s, lo, hi); k >= 0; k = nextset(s, k+1, hi) {
+			if !yield(k - lo) {
+				return
+			}
+		}
+	}
+}
+
+func zeros(s []Bit, lo, hi int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for k := nextclr(s, lo, hi); k >= 0; k = nextclr(s, k+1, hi) {
+			if !yield(k - lo) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package bitarray
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// slowNext returns the first position at or after `from` in s holding c, or -1.
+func slowNext(s string, from int, c byte) int {
+	for k := max(from, 0); k < len(s); k++ {
+		if s[k] == c {
+			return k
+		}
+	}
+	return -1
+}
+
+// slowPrev returns the last position at or before `from` in s holding c, or -1.
+func slowPrev(s string, from int, c byte) int {
+	for k := min(from, len(s)-1); k >= 0; k-- {
+		if s[k] == c {
+			return k
+		}
+	}
+	return -1
+}
+
+func TestNextPrev(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for _, n := range []int{0, 1, 63, 64, 65, 200, 513} {
+		for _, density := range []float32{0, 0.01, 0.5, 0.99, 1} {
+			ba := New(n)
+			for k := 0; k < n; k++ {
+				if rng.Float32() < density {
+					ba.Set(k)
+				}
+			}
+			s := ba.String()
+
+			for from := -2; from < n+2; from++ {
+				fns := []struct {
+					name string
+					fn   func(int) (int, bool)
+					ref  int
+				}{
+					{"nextset", ba.NextSet, slowNext(s, from, '1')},
+					{"nextclr", ba.NextClr, slowNext(s, from, '0')},
+					{"prevset", ba.PrevSet, slowPrev(s, from, '1')},
+					{"prevclr", ba.PrevClr, slowPrev(s, from, '0')},
+				}
+				for _, f := range fns {
+					k, ok := f.fn(from)
+					if ok != (f.ref >= 0) || (ok && k != f.ref) {
+						t.Fatalf("Test %s(%d) of %s failed. got = %d, %t, exp = %d\n", f.name, from, s, k, ok, f.ref)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestNextPrevRange(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for i := 0; i < 300; i++ {
+		ba := New(1 + rng.Intn(400))
+		randomize(&ba, rng)
+		b := rng.Intn(ba.n)
+		n := rng.Intn(ba.n - b + 1)
+		r := ba.Range(b, n)
+		s := ba.String()[b : b+n]
+
+		for from := -1; from <= n; from++ {
+			fns := []struct {
+				name string
+				fn   func(int) (int, bool)
+				ref  int
+			}{
+				{"nextset", r.NextSet, slowNext(s, from, '1')},
+				{"nextclr", r.NextClr, slowNext(s, from, '0')},
+				{"prevset", r.PrevSet, slowPrev(s, from, '1')},
+				{"prevclr", r.PrevClr, slowPrev(s, from, '0')},
+			}
+			for _, f := range fns {
+				k, ok := f.fn(from)
+				if ok != (f.ref >= 0) || (ok && k != f.ref) {
+					t.Fatalf("Test %s(%d) of (%d, %d) failed. got = %d, %t, exp = %d\n", f.name, from, b, n, k, ok, f.ref)
+				}
+			}
+		}
+	}
+
+	t.Run("extremes", func(t *testing.T) {
+		ba := New(100)
+		ba.SetAll()
+		r := ba.Range(10, 50)
+		for _, from := range []int{math.MinInt, math.MaxInt} {
+			for name, fn := range map[string]func(int) (int, bool){
+				"nextset": r.NextSet, "nextclr": r.NextClr, "prevset": r.PrevSet, "prevclr": r.PrevClr,
+			} {
+				exp := -1
+				if from < 0 && name == "nextset" {
+					exp = 0
+				} else if from > 0 && name == "prevset" {
+					exp = 49
+				}
+				if k, ok := fn(from); ok != (exp >= 0) || (ok && k != exp) {
+					t.Fatalf("Test %s(%d) failed. got = %d, %t, exp = %d\n", name, from, k, ok, exp)
+				}
+			}
+		}
+	})
+}
+
+func TestIter(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	ba := New(300)
+	randomize(&ba, rng)
+	s := ba.String()
+
+	t.Run("all", func(t *testing.T) {
+		sb := make([]byte, 0, ba.n)
+		for k, b := range ba.All() {
+			if k != len(sb) {
+				t.Fatalf("Test failed. got = %d, exp = %d\n", k, len(sb))
+			}
+			sb = append(sb, '0')
+			if b {
+				sb[k] = '1'
+			}
+		}
+		if string(sb) != s {
+			t.Fatalf("Test failed. got = %s\nexp = %s\n", sb, s)
+		}
+	})
+
+	t.Run("ones and zeros", func(t *testing.T) {
+		sb := make([]byte, ba.n)
+		for k := range ba.Ones() {
+			sb[k] = '1'
+		}
+		for k := range ba.Zeros() {
+			if sb[k] != 0 {
+				t.Fatalf("Test failed. bit %d reported as both set and clear\n", k)
+			}
+			sb[k] = '0'
+		}
+		if string(sb) != s {
+			t.Fatalf("Test failed. got = %s\nexp = %s\n", sb, s)
+		}
+	})
+
+	t.Run("range", func(t *testing.T) {
+		const b, n = 37, 150
+		r := ba.Range(b, n)
+		sb := make([]byte, n)
+		for k, v := range r.All() {
+			sb[k] = '0'
+			if v {
+				sb[k] = '1'
+			}
+		}
+		if string(sb) != s[b:b+n] {
+			t.Fatalf("Test failed. got = %s\nexp = %s\n", sb, s[b:b+n])
+		}
+
+		cnt := 0
+		for k := range r.Ones() {
+			if s[b+k] != '1' {
+				t.Fatalf("Test failed. bit %d is not set\n", k)
+			}
+			cnt++
+		}
+		for k := range r.Zeros() {
+			if s[b+k] != '0' {
+				t.Fatalf("Test failed. bit %d is not clear\n", k)
+			}
+			cnt++
+		}
+		if cnt != n {
+			t.Fatalf("Test failed. got = %d, exp = %d\n", cnt, n)
+		}
+	})
+
+	t.Run("break", func(t *testing.T) {
+		cnt := 0
+		for range ba.Ones() {
+			cnt++
+			if cnt == 3 {
+				break
+			}
+		}
+		if cnt != min(3, ba.Cnt()) {
+			t.Fatalf("Test failed. got = %d, exp = %d\n", cnt, min(3, ba.Cnt()))
+		}
+	})
+}
+
+func BenchmarkIter(b *testing.B) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	b.Run("ones", func(b *testing.B) {
+		b.ReportAllocs()
+		b.StopTimer()
+		ba := New(257)
+		randomize(&ba, rng)
+		b.StartTimer()
+
+		for i := 0; i < b.N; i++ {
+			for range ba.Ones() {
+			}
+		}
+	})
+
+	b.Run("nextset, sparse", func(b *testing.B) {
+		b.ReportAllocs()
+		b.StopTimer()
+		ba := New(4096)
+		ba.Set(4000)
+		b.StartTimer()
+
+		for i := 0; i < b.N; i++ {
+			ba.NextSet(0)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package bitarray
+
+// The bitwise operations below work a block at a time. When the operands differ
+// in size, the missing bits of the shorter operand are treated as zero and the
+// result is truncated to the size of the destination. The unused bits of the
+// destination's last block are always left cleared.
+
+// And sets ba to the bitwise AND of ba and o.
+func (ba *BitArray) And(o *BitArray) { And(ba, ba, o) }
+
+// Or sets ba to the bitwise OR of ba and o.
+func (ba *BitArray) Or(o *BitArray) { Or(ba, ba, o) }
+
+// Xor sets ba to the bitwise XOR of ba and o.
+func (ba *BitArray) Xor(o *BitArray) { Xor(ba, ba, o) }
+
+// AndNot clears the bits in ba that are set in o.
+func (ba *BitArray) AndNot(o *BitArray) { AndNot(ba, ba, o) }
+
+// Not inverts all the bits.
+func (ba *BitArray) Not() { Not(ba, ba) }
+
+// And stores the bitwise AND of a and b into dst.
+func And(dst, a, b *BitArray) {
+	for i := range dst.bits {
+		dst.bits[i] = a.blk(i) & b.blk(i)
+	}
+	dst.clrTail()
+}
+
+// Or stores the bitwise OR of a and b into dst.
+func Or(dst, a, b *BitArray) {
+	for i := range dst.bits {
+		dst.bits[i] = a.blk(i) | b.blk(i)
+	}
+	dst.clrTail()
+}
+
+// Xor stores the bitwise XOR of a and b into dst.
+func Xor(dst, a, b *BitArray) {
+	for i := range dst.bits {
+		dst.bits[i] = a.blk(i) ^ b.blk(i)
+	}
+	dst.clrTail()
+}
+
+// AndNot stores the bits of a that are not set in b into dst.
+func AndNot(dst, a, b *BitArray) {
+	for i := range dst.bits {
+		dst.bits[i] = a.blk(i) &^ b.blk(i)
+	}
+	dst.clrTail()
+}
+
+// Not stores the bitwise complement of src into dst.
+// Bits of dst beyond src.Size() are set.
+func Not(dst, src *BitArray) {
+	for i := range dst.bits {
+		dst.bits[i] = ^src.blk(i)
+	}
+	dst.clrTail()
+}
+
+// The range variants below combine the bits of `src` into `dst`, one word at a time,
+// for any pair of starting positions. The procedure processes number of bits equal to
+// the minimum of the two ranges. It is undefined behavior to combine overlapping ranges.
+
+// AndRange stores the bitwise AND of dst and src into dst.
+func AndRange(dst, src Range) { rangeop(dst, src, func(d, s Bit) Bit { return d & s }) }
+
+// OrRange stores the bitwise OR of dst and src into dst.
+func OrRange(dst, src Range) { rangeop(dst, src, func(d, s Bit) Bit { return d | s }) }
+
+// XorRange stores the bitwise XOR of dst and src into dst.
+func XorRange(dst, src Range) { rangeop(dst, src, func(d, s Bit) Bit { return d ^ s }) }
+
+// AndNotRange clears the bits in dst that are set in src.
+func AndNotRange(dst, src Range) { rangeop(dst, src, func(d, s Bit) Bit { return d &^ s }) }
+
+// NotRange stores the bitwise complement of src into dst.
+func NotRange(dst, src Range) { rangeop(dst, src, func(_, s Bit) Bit { return ^s }) }
+
+func rangeop(dst, src Range, op func(d, s Bit) Bit) {
+	nb := min(dst.n, src.n)
+	for k := 0; k < nb; k += 64 {
+		w := min(64, nb-k)
+		s := getbits(src.ba.bits, src.b+k, w)
+		d := getbits(dst.ba.bits, dst.b+k, w)
+		putbits(dst.ba.bits, dst.b+k, w, op(d, s))
+	}
+}
@@ -0,0 +1,197 @@
+package bitarray
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowLogic applies op bit-by-bit, treating bits beyond the size of an operand as zero.
+func slowLogic(n int, a, b *BitArray, op func(x, y bool) bool) string {
+	sb := make([]byte, n)
+	for i := range sb {
+		x := i < a.n && a.Chk(i)
+		y := i < b.n && b.Chk(i)
+		sb[i] = '0'
+		if op(x, y) {
+			sb[i] = '1'
+		}
+	}
+	return string(sb)
+}
+
+func TestLogic(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	ops := []struct {
+		name string
+		fn   func(dst, a, b *BitArray)
+		ref  func(x, y bool) bool
+	}{
+		{"and", And, func(x, y bool) bool { return x && y }},
+		{"or", Or, func(x, y bool) bool { return x || y }},
+		{"xor", Xor, func(x, y bool) bool { return x != y }},
+		{"andnot", AndNot, func(x, y bool) bool { return x && !y }},
+	}
+
+	sizes := [][3]int{
+		{0, 0, 0},
+		{7, 7, 7},
+		{64, 64, 64},
+		{257, 257, 257},
+		{257, 130, 300},
+		{100, 257, 3},
+		{600, 513, 64},
+	}
+
+	for _, op := range ops {
+		t.Run(op.name, func(t *testing.T) {
+			for _, sz := range sizes {
+				dst, a, b := New(sz[0]), New(sz[1]), New(sz[2])
+				randomize(&dst, rng)
+				randomize(&a, rng)
+				randomize(&b, rng)
+				exp := slowLogic(dst.n, &a, &b, op.ref)
+				op.fn(&dst, &a, &b)
+				if dst.String() != exp {
+					t.Fatalf("Test %v failed. got = %s\nexp = %s\n", sz, dst.String(), exp)
+				}
+				if dst.Cnt() != strings.Count(exp, "1") {
+					t.Fatalf("Test %v failed. garbage in unused bits of the last block\n", sz)
+				}
+			}
+		})
+	}
+
+	t.Run("in-place", func(t *testing.T) {
+		a, b := New(257), New(131)
+		randomize(&a, rng)
+		randomize(&b, rng)
+		exp := slowLogic(a.n, &a, &b, func(x, y bool) bool { return x || y })
+		a.Or(&b)
+		if a.String() != exp {
+			t.Fatalf("Test failed. got = %s\nexp = %s\n", a.String(), exp)
+		}
+
+		exp = slowLogic(a.n, &a, &b, func(x, y bool) bool { return x && !y })
+		a.AndNot(&b)
+		if a.String() != exp {
+			t.Fatalf("Test failed. got = %s\nexp = %s\n", a.String(), exp)
+		}
+	})
+
+	t.Run("not", func(t *testing.T) {
+		for _, n := range []int{0, 1, 63, 64, 65, 257, 600} {
+			ba := New(n)
+			randomize(&ba, rng)
+			exp := slowLogic(n, &ba, &ba, func(x, _ bool) bool { return !x })
+			ba.Not()
+			if ba.String() != exp {
+				t.Fatalf("Test failed. got = %s\nexp = %s\n", ba.String(), exp)
+			}
+			if ba.Cnt() != strings.Count(exp, "1") {
+				t.Fatalf("Test %d failed. garbage in unused bits of the last block\n", n)
+			}
+		}
+
+		src, dst := New(10), New(70)
+		src.SetAll()
+		Not(&dst, &src)
+		if exp := "0000000000111111111111111111111111111111111111111111111111111111111111"; dst.String() != exp {
+			t.Fatalf("Test failed. got = %s\nexp = %s\n", dst.String(), exp)
+		}
+	})
+
+	t.Run("setall", func(t *testing.T) {
+		ba := New(257)
+		ba.SetAll()
+		if ba.Cnt() != ba.n {
+			t.Fatalf("Test failed. got = %d, exp = %d\n", ba.Cnt(), ba.n)
+		}
+	})
+}
+
+func TestLogicRange(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	ops := []struct {
+		name string
+		fn   func(dst, src Range)
+		ref  func(d, s byte) byte
+	}{
+		{"and", AndRange, func(d, s byte) byte { return d & s }},
+		{"or", OrRange, func(d, s byte) byte { return d | s }},
+		{"xor", XorRange, func(d, s byte) byte { return d ^ s }},
+		{"andnot", AndNotRange, func(d, s byte) byte { return d &^ s }},
+		{"not", NotRange, func(_, s byte) byte { return s ^ 1 }},
+	}
+
+	for _, op := range ops {
+		t.Run(op.name, func(t *testing.T) {
+			for i := 0; i < 500; i++ {
+				dst, src := New(1+rng.Intn(300)), New(1+rng.Intn(300))
+				randomize(&dst, rng)
+				randomize(&src, rng)
+				db, sb := rng.Intn(dst.n), rng.Intn(src.n)
+				dn, sn := rng.Intn(dst.n-db+1), rng.Intn(src.n-sb+1)
+
+				ds, ss := []byte(dst.String()), src.String()
+				for k := 0; k < min(dn, sn); k++ {
+					ds[db+k] = '0' + op.ref(ds[db+k]-'0', ss[sb+k]-'0')
+				}
+
+				op.fn(dst.Range(db, dn), src.Range(sb, sn))
+				if dst.String() != string(ds) {
+					t.Fatalf("Test (%d, %d) <- (%d, %d) failed. got = %s\nexp = %s\n", db, dn, sb, sn, dst.String(), ds)
+				}
+				if src.String() != ss {
+					t.Fatalf("Test (%d, %d) <- (%d, %d) failed. src modified\n", db, dn, sb, sn)
+				}
+			}
+		})
+	}
+
+	t.Run("37-bit field", func(t *testing.T) {
+		a := New(200)
+		b := New(200)
+		randomize(&b, rng)
+		OrRange(a.Range(101, 37), b.Range(13, 37))
+		exp := strings.Repeat("0", 101) + b.String()[13:50] + strings.Repeat("0", 62)
+		if a.String() != exp {
+			t.Fatalf("Test failed. got = %s\nexp = %s\n", a.String(), exp)
+		}
+	})
+}
+
+func BenchmarkLogic(b *testing.B) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	b.Run("or", func(b *testing.B) {
+		b.ReportAllocs()
+		b.StopTimer()
+		b1 := New(4096)
+		b2 := New(b1.n)
+		randomize(&b1, rng)
+		randomize(&b2, rng)
+		b.StartTimer()
+
+		for i := 0; i < b.N; i++ {
+			b1.Or(&b2)
+		}
+	})
+
+	b.Run("or-range, unaligned", func(b *testing.B) {
+		b.ReportAllocs()
+		b.StopTimer()
+		b1 := New(4096)
+		b2 := New(b1.n)
+		randomize(&b1, rng)
+		randomize(&b2, rng)
+		dr, sr := b1.Range(47, 4000), b2.Range(67, 4000)
+		b.StartTimer()
+
+		for i := 0; i < b.N; i++ {
+			OrRange(dr, sr)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package bitarray
+
+// PackedInts is an array of fixed-width integers stored back to back in a BitArray.
+// Value i occupies bits [i*w, (i+1)*w) and may straddle two blocks. Values wider than
+// the width are truncated when stored. Signed values are stored in two's complement and
+// sign-extended when read.
+type PackedInts struct {
+	ba BitArray
+	w  int // width of a value in bits
+	n  int // no. of values
+}
+
+// NewPackedInts creates a PackedInts of n values, each `width` bits wide, all zero.
+// width must be between 1 and 64.
+func NewPackedInts(n, width int) *PackedInts {
+	if width < 1 || width > 64 {
+		panic("width must be between 1 and 64")
+	}
+	return &PackedInts{ba: New(n * width), w: width, n: n}
+}
+
+// Len returns the no. of values.
+func (p *PackedInts) Len() int { return p.n }
+
+// Width returns the width of a value in bits.
+func (p *PackedInts) Width() int { return p.w }
+
+// Get returns the value at position i.
+func (p *PackedInts) Get(i int) uint64 {
+	if uint(i) >= uint(p.n) {
+		panic("index out of bounds")
+	}
+	return getbits(p.ba.bits, i*p.w, p.w)
+}
+
+// Put sets the value at position i to the low `width` bits of v.
+func (p *PackedInts) Put(i int, v uint64) {
+	if uint(i) >= uint(p.n) {
+		panic("index out of bounds")
+	}
+	putbits(p.ba.bits, i*p.w, p.w, v)
+}
+
+// GetInt returns the value at position i, sign-extended.
+func (p *PackedInts) GetInt(i int) int64 {
+	sh := 64 - p.w
+	return int64(p.Get(i)<<sh) >> sh
+}
+
+// PutInt sets the value at position i to the low `width` bits of v.
+func (p *PackedInts) PutInt(i int, v int64) { p.Put(i, uint64(v)) }
+
+// Decode unpacks the values starting at position `from` into dst and returns the no. of
+// values unpacked, the smaller of len(dst) and the no. of values from `from` to the end.
+func (p *PackedInts) Decode(from int, dst []uint64) int { return decode(p, from, dst, 0) }
+
+// DecodeInt is like Decode, but sign-extends the values.
+func (p *PackedInts) DecodeInt(from int, dst []int64) int { return decode(p, from, dst, uint(64-p.w)) }
+
+// Encode packs the values of src into positions `from` onwards.
+// It panics if they do not fit.
+func (p *PackedInts) Encode(from int, src []uint64) { encode(p, from, src) }
+
+// EncodeInt is like Encode, for signed values.
+func (p *PackedInts) EncodeInt(from int, src []int64) { encode(p, from, src) }
+
+// decode unpacks the values a block at a time, keeping the bits of the current block
+// that are not consumed yet in an accumulator. Each value is shifted left and then
+// arithmetically right by sh to sign-extend it, or left as is if sh is 0.
+func decode[T uint64 | int64](p *PackedInts, from int, dst []T, sh uint) int {
+	if from < 0 || from > p.n {
+		panic("index out of bounds")
+	}
+	n := min(len(dst), p.n-from)
+	if n == 0 {
+		return 0
+	}
+
+	w, m := p.w, lomask(p.w)
+	bi, si := biandsi(from * w)
+	acc, nb := p.ba.bits[bi]>>si, 64-int(si) // the unconsumed bits and their no.
+	for j := 0; j < n; j++ {
+		var v uint64
+		if nb >= w {
+			v = acc & m
+			acc >>= w
+			nb -= w
+		} else {
+			// the value continues in the next block
+			bi++
+			u := p.ba.bits[bi]
+			v = (acc | u<<nb) & m
+			acc = u >> (w - nb)
+			nb += 64 - w
+		}
+		dst[j] = T(int64(v<<sh) >> sh)
+	}
+	return n
+}
+
+// encode packs the values a block at a time, gathering them in an accumulator that is
+// stored once full.
+func encode[T uint64 | int64](p *PackedInts, from int, src []T) {
+	if from < 0 || from+len(src) > p.n {
+		panic("index out of bounds")
+	}
+	if len(src) == 0 {
+		return
+	}
+
+	w, m := p.w, lomask(p.w)
+	bi, si := biandsi(from * w)
+	acc, nb := p.ba.bits[bi]&lomask(int(si)), int(si) // keep the bits before the first value
+	for _, x := range src {
+		v := uint64(x) & m
+		acc |= v << nb
+		if nb+w < 64 {
+			nb += w
+			continue
+		}
+		p.ba.bits[bi] = acc
+		bi++
+		acc = v >> (64 - nb)
+		nb += w - 64
+	}
+	if nb > 0 {
+		// keep the bits after the last value
+		p.ba.bits[bi] = acc | p.ba.bits[bi]&^lomask(nb)
+	}
+}
@@ -0,0 +1,153 @@
+package bitarray
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestPackedInts(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for w := 1; w <= 64; w++ {
+		const n = 300
+		m := lomask(w)
+		p := NewPackedInts(n, w)
+		exp := make([]uint64, n)
+		for i := range exp {
+			exp[i] = rng.Uint64()
+			p.Put(i, exp[i])
+			exp[i] &= m
+		}
+		for i := range exp {
+			if got := p.Get(i); got != exp[i] {
+				t.Fatalf("Test Get(%d), width %d failed. got = %x, exp = %x\n", i, w, got, exp[i])
+			}
+		}
+		if p.Len() != n || p.Width() != w || p.ba.Size() != n*w {
+			t.Fatalf("Test width %d failed. got = %d values, %d bits\n", w, p.Len(), p.ba.Size())
+		}
+
+		// decode from every start
+		dst := make([]uint64, n+5)
+		for from := 0; from <= n; from++ {
+			k := p.Decode(from, dst)
+			if k != n-from {
+				t.Fatalf("Test Decode(%d), width %d failed. got = %d, exp = %d\n", from, w, k, n-from)
+			}
+			for j := 0; j < k; j++ {
+				if dst[j] != exp[from+j] {
+					t.Fatalf("Test Decode(%d), width %d failed. value %d got = %x, exp = %x\n", from, w, j, dst[j], exp[from+j])
+				}
+			}
+		}
+
+		// encode a slice in the middle, leaving the values around it alone
+		from, cnt := rng.Intn(n), 0
+		cnt = rng.Intn(n - from + 1)
+		src := make([]uint64, cnt)
+		for j := range src {
+			src[j] = rng.Uint64()
+			exp[from+j] = src[j] & m
+		}
+		p.Encode(from, src)
+		for i := range exp {
+			if got := p.Get(i); got != exp[i] {
+				t.Fatalf("Test Encode(%d, %d values), width %d failed. value %d got = %x, exp = %x\n", from, cnt, w, i, got, exp[i])
+			}
+		}
+	}
+
+	for name, fn := range map[string]func(p *PackedInts){
+		"get past end":    func(p *PackedInts) { p.Get(5) },
+		"put past end":    func(p *PackedInts) { p.Put(5, 31) },
+		"getint negative": func(p *PackedInts) { p.GetInt(-1) },
+		"putint past end": func(p *PackedInts) { p.PutInt(5, -1) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := NewPackedInts(3, 5)
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("Test failed. expected a panic\n")
+				}
+				if p.ba.Cnt() != 0 {
+					t.Fatalf("Test failed. got = %d set bits, exp = %d\n", p.ba.Cnt(), 0)
+				}
+			}()
+			fn(p)
+		})
+	}
+}
+
+func TestPackedIntsSigned(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for _, w := range []int{1, 5, 7, 12, 33, 63, 64} {
+		const n = 200
+		lo, sh := int64(-1)<<(w-1), 64-w
+		p := NewPackedInts(n, w)
+		exp := make([]int64, n)
+		for i := range exp {
+			exp[i] = int64(rng.Uint64()<<sh) >> sh
+		}
+
+		p.EncodeInt(0, exp)
+		for i := range exp {
+			if got := p.GetInt(i); got != exp[i] {
+				t.Fatalf("Test GetInt(%d), width %d failed. got = %d, exp = %d\n", i, w, got, exp[i])
+			}
+		}
+
+		p.PutInt(7, lo)
+		exp[7] = lo
+		dst := make([]int64, n)
+		p.DecodeInt(0, dst)
+		for i := range exp {
+			if dst[i] != exp[i] {
+				t.Fatalf("Test DecodeInt, width %d failed. value %d got = %d, exp = %d\n", w, i, dst[i], exp[i])
+			}
+		}
+	}
+}
+
+func BenchmarkPackedInts(b *testing.B) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	const n = 1 << 16
+	p := NewPackedInts(n, 12)
+	vals := make([]uint64, n)
+	for i := range vals {
+		vals[i] = rng.Uint64()
+	}
+
+	b.Run("put", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for j, v := range vals {
+				p.Put(j, v)
+			}
+		}
+	})
+
+	b.Run("encode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			p.Encode(0, vals)
+		}
+	})
+
+	b.Run("get", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for j := range vals {
+				vals[j] = p.Get(j)
+			}
+		}
+	})
+
+	b.Run("decode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			p.Decode(0, vals)
+		}
+	})
+}
@@ -1,71 +1,126 @@
 package bitarray
 
+import "math/bits"
+
 // A Range represents a span over a certain number of bits in a BitArray starting
-// at specific position.
+// at specific position. It is a view: its methods read and write the bits of the
+// BitArray, but take positions relative to the start of the range and never touch
+// bits outside it.
 type Range struct {
-	*BitArray
+	ba   *BitArray
 	b, n int
 }
 
 // Range creates a Range object representing `n` bits starting at `b`.
+// An empty range may start at the end of the array.
 func (ba *BitArray) Range(b, n int) Range {
-	if (b < ba.n) && (b+n-1 < ba.n) {
+	if ba.inRange(b, n) {
 		return Range{ba, b, n}
 	}
 	panic("index out of bounds")
 }
 
-// CopyRange copies bits from `src` into `dst` specified by the ranges.
-// The procedure copies number of bits equal to the the minimum of the two ranges.
-// It is undefined behavior to copy overlapping ranges.
-func CopyRange(dst, src Range) {
-	nb := min(dst.n, src.n) // no. of bits to get
+// Sub creates a Range representing `n` bits starting at `b` within r.
+// An empty range may start at the end of r.
+func (r Range) Sub(b, n int) Range {
+	if inSpan(b, n, r.n) {
+		return Range{r.ba, r.b + b, n}
+	}
+	panic("index out of bounds")
+}
 
-	dbi, dsi := biandsi(dst.b)
-	sbi, ssi := biandsi(src.b)
+// Size returns the no. of bits in the range.
+func (r Range) Size() int { return r.n }
 
-	if dsi == 0 && ssi == 0 {
-		m, n := biandsi(nb) // nb/64, nb%64
-		copy(dst.bits[dbi:dbi+m], src.bits[sbi:sbi+m])
-		if n != 0 {
-			// copy remaining < 64 bits, one bit at a time
-			copyu64bits(n, &dst.bits[dbi+m], src.bits[sbi+m])
-		}
-		return
+// Set sets the bit at position k.
+func (r Range) Set(k int) { r.ba.Set(r.pos(k)) }
+
+// Clr clears the bit at position k.
+func (r Range) Clr(k int) { r.ba.Clr(r.pos(k)) }
+
+// Tgl toggles the bit at position k.
+func (r Range) Tgl(k int) { r.ba.Tgl(r.pos(k)) }
+
+// Chk returns the value of the bit at position k.
+func (r Range) Chk(k int) bool { return r.ba.Chk(r.pos(k)) }
+
+// Put sets the value of the bit at position k to v.
+func (r Range) Put(k int, v Bit) { r.ba.Put(r.pos(k), v) }
+
+// SetAll sets all the bits in the range.
+func (r Range) SetAll() { fillbits(r.ba.bits, r.b, r.n, One) }
+
+// ClrAll clears all the bits in the range.
+func (r Range) ClrAll() { fillbits(r.ba.bits, r.b, r.n, Zero) }
+
+// Cnt returns the number of set bits in the range.
+func (r Range) Cnt() (n int) {
+	for k := 0; k < r.n; k += 64 {
+		n += bits.OnesCount64(getbits(r.ba.bits, r.b+k, min(64, r.n-k)))
 	}
+	return
+}
 
-	if dst.b == src.b {
-		bi, si := biandsi(dst.b)
+// Clone returns a new BitArray holding a copy of the bits in the range.
+func (r Range) Clone() (c BitArray) {
+	c = New(r.n)
+	movebits(c.bits, 0, r.ba.bits, r.b, r.n)
+	return
+}
 
-		// wind the first block till the next boundary
-		for ; si < 64; si++ {
-			if nb != 0 {
-				setbit(&dst.bits[bi], si, getbit(src.bits[bi], si))
-			} else {
-				return
-			}
-		}
-		// we've wound up to the next boundary
-		// so we'll copy blocks up until the last-but-one block
-		bi++
-		m, n := biandsi(nb) // nb/64, nb%64
-		copy(dst.bits[bi:bi+m], src.bits[bi:bi+m])
-
-		if n != 0 {
-			// copy the remaining bits from the last block
-			copyu64bits(n, &dst.bits[bi+m], src.bits[bi+m])
+func (r Range) String() string {
+	sb := make([]byte, r.n)
+	for i := range sb {
+		sb[i] = '0'
+		if r.ba.Chk(r.b + i) {
+			sb[i] = '1'
 		}
-		return
 	}
+	return string(sb)
+}
 
-	unalignedCopy(nb, dst.bits, dbi, dsi, src.bits, sbi, ssi)
+// pos returns the position in the BitArray of the bit at position k in the range.
+func (r Range) pos(k int) int {
+	if k < 0 || k >= r.n {
+		panic("index out of bounds")
+	}
+	return r.b + k
 }
 
-func copyu64bits(n uint64, d *uint64, s uint64) {
-	for i := uint64(0); n != 0; i++ {
-		setbit(d, i, getbit(s, i))
-		n--
+// CopyRange copies bits from `src` into `dst` specified by the ranges.
+// The procedure copies number of bits equal to the the minimum of the two ranges.
+// The ranges may overlap, in which case the result is as if `src` were copied
+// to a temporary first.
+func CopyRange(dst, src Range) {
+	nb := min(dst.n, src.n) // no. of bits to copy
+	if nb == 0 {
+		return
+	}
+
+	if &dst.ba.bits[0] == &src.ba.bits[0] && src.b < dst.b && dst.b < src.b+nb {
+		// dst overlaps the end of src, so copy from the end down to read every bit of src
+		// before it is overwritten. Every other case is safe to copy upwards.
+		movebits(dst.ba.bits, dst.b, src.ba.bits, src.b, nb)
+		return
+	}
+
+	dbi, dsi := biandsi(dst.b)
+	sbi, ssi := biandsi(src.b)
+
+	if dsi != 0 {
+		// copy up to the next block boundary of dst, so that the rest of dst is aligned
+		w := min(nb, 64-int(dsi))
+		putbits(dst.ba.bits, dst.b, w, getbits(src.ba.bits, src.b, w))
+		nb -= w
+		dbi++
+		sbi, ssi = biandsi(src.b + w)
 	}
+
+	if ssi == 0 {
+		alignedCopy(nb, dst.ba.bits[dbi:], src.ba.bits[sbi:])
+		return
+	}
+	unalignedCopy(nb, dst.ba.bits[dbi:], src.ba.bits[sbi:], ssi)
 }
 
 // SwapRange swaps the bits of two ranges `a` and `b`.
@@ -73,142 +128,225 @@ func copyu64bits(n uint64, d *uint64, s uint64) {
 // It is undefined behavior to swap overlapping ranges.
 func SwapRange(a, b Range) {
 	nb := min(a.n, b.n) // no. of bits to swap
+	if nb == 0 {
+		return
+	}
 
 	abi, asi := biandsi(a.b)
 	bbi, bsi := biandsi(b.b)
 
-	if asi == 0 && bsi == 0 {
-		m, n := biandsi(nb) // nb/64, nb%64
-		// 	swap the aligned blocks first
-		for m != 0 {
-			a.bits[abi], b.bits[bbi] = b.bits[bbi], a.bits[abi]
-			abi++
-			bbi++
-			m--
-		}
+	if asi != 0 {
+		// swap up to the next block boundary of a, so that the rest of a is aligned
+		w := min(nb, 64-int(asi))
+		u, v := getbits(a.ba.bits, a.b, w), getbits(b.ba.bits, b.b, w)
+		putbits(a.ba.bits, a.b, w, v)
+		putbits(b.ba.bits, b.b, w, u)
+		nb -= w
+		abi++
+		bbi, bsi = biandsi(b.b + w)
+	}
 
-		if n != 0 {
-			// 	swap the bits of the last block, bit-by-bit
-			swapu64bits(n, &a.bits[abi], &b.bits[bbi])
-		}
+	if bsi == 0 {
+		alignedSwap(nb, a.ba.bits[abi:], b.ba.bits[bbi:])
 		return
 	}
+	unalignedSwap(nb, a.ba.bits[abi:], b.ba.bits[bbi:], bsi)
+}
 
-	// starting point is same
-	if a.b == b.b {
-		bi, si := biandsi(a.b)
-		// process the first block
-		anum, bnum := &a.bits[bi], &b.bits[bi]
-		for ; si < 64; si++ {
-			if nb != 0 {
-				abit := getbit(*anum, si)
-				bbit := getbit(*bnum, si)
-				setbit(anum, si, bbit)
-				setbit(bnum, si, abit)
-				nb--
-			} else {
-				return
-			}
-		}
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
 
-		// 	we've wound up to the beginning of the next boundary
-		// process from the second till the last-but-one block
-		bi++
-		m, n := biandsi(nb) // nb/64, nb%64
-		// swap until the last block
-		for m != 0 {
-			a.bits[bi], b.bits[bi] = b.bits[bi], a.bits[bi]
-			bi++
-			m--
-		}
+// getbits returns n <= 64 bits of s starting at bit position k in the low bits of the result.
+func getbits(s []Bit, k, n int) Bit {
+	bi, si := biandsi(k)
+	v := s[bi] >> si
+	if si != 0 && int(si)+n > 64 {
+		v |= s[bi+1] << (64 - si)
+	}
+	return v & lomask(n)
+}
+
+// putbits writes the low n <= 64 bits of v into d starting at bit position k.
+func putbits(d []Bit, k, n int, v Bit) {
+	bi, si := biandsi(k)
+	m := lomask(n)
+	v &= m
+	d[bi] = d[bi]&^(m<<si) | v<<si
+	if si != 0 && int(si)+n > 64 {
+		d[bi+1] = d[bi+1]&^(m>>(64-si)) | v>>(64-si)
+	}
+}
 
-		if n != 0 {
-			// swap the remaining bits, if any, in the last block bitwise
-			swapu64bits(n, &a.bits[bi], &b.bits[bi])
+// movebits copies n bits of s starting at bit position sk into d starting at bit position dk,
+// 64 bits at a time. If d and s are the same slice, the copy proceeds in the direction that
+// reads every source bit before it is overwritten.
+func movebits(d []Bit, dk int, s []Bit, sk, n int) {
+	if dk <= sk {
+		for k := 0; k < n; k += 64 {
+			w := min(64, n-k)
+			putbits(d, dk+k, w, getbits(s, sk+k, w))
 		}
 		return
 	}
 
-	// 	general case
-	unalignedSwap(nb, a.bits, abi, asi, b.bits, bbi, bsi)
+	for k := n; k > 0; k -= 64 {
+		w := min(64, k)
+		putbits(d, dk+k-w, w, getbits(s, sk+k-w, w))
+	}
 }
 
-func min(a, b int) int {
-	if a < b {
-		return a
+// revbits reverses the order of n bits of d starting at bit position k, swapping 64 bits
+// from each end at a time.
+func revbits(d []Bit, k, n int) {
+	lo, hi := k, k+n
+	for ; hi-lo >= 128; lo, hi = lo+64, hi-64 {
+		u, v := getbits(d, lo, 64), getbits(d, hi-64, 64)
+		putbits(d, lo, 64, bits.Reverse64(v))
+		putbits(d, hi-64, 64, bits.Reverse64(u))
 	}
-	return b
-}
 
-func getbit(u uint64, i uint64) Bit     { return (u >> i) & 1 }
-func setbit(u *uint64, i uint64, b Bit) { *u = (*u & ^(1 << i)) | (b << i) }
+	switch m := hi - lo; {
+	case m > 64:
+		// the first 64 bits end up last, and the other w bits first
+		w := m - 64
+		u, v := getbits(d, lo, 64), getbits(d, lo+64, w)
+		putbits(d, lo, w, bits.Reverse64(v)>>(64-w))
+		putbits(d, lo+w, 64, bits.Reverse64(u))
+	case m > 0:
+		putbits(d, lo, m, bits.Reverse64(getbits(d, lo, m))>>(64-m))
+	}
+}
 
-func alignedcopy(nb int, dst []Bit, di uint64, src []Bit, si uint64) (int, uint64, uint64) {
-	m := uint64(nb / 64) // no. of blocks to copy
-	copy(dst[di:di+m], src[si:si+m])
-	// for nb != 0 {
-	// 	dst[di] = src[si]
-	// 	di++
-	// 	si++
-	// 	nb -= 64
-	// }
-	return nb, di + m, si + m
+// rotbits rotates n bits of d starting at bit position k towards higher positions by
+// 0 <= r <= n, in place: reversing all the bits and then the first r and the last n-r
+// of them puts every bit r positions higher, modulo n.
+func rotbits(d []Bit, k, n, r int) {
+	revbits(d, k, n)
+	revbits(d, k, r)
+	revbits(d, k+r, n-r)
 }
 
-func unalignedCopy(nb int, d []Bit, dbi, dsi uint64, s []Bit, sbi, ssi uint64) {
-	for nb != 0 {
-		// dst[dbi][dsi] <- src[sbi][ssi]
-		setbit(&d[dbi], dsi, getbit(s[sbi], ssi))
+// fillbits sets n bits of d starting at bit position k to v, a block at a time.
+func fillbits(d []Bit, k, n int, v Bit) {
+	if n <= 0 {
+		return
+	}
+	var u Bit
+	if v != 0 {
+		u = ^u
+	}
 
-		// move to the next src bit
-		ssi++
-		if ssi >= 64 {
-			sbi++
-			ssi = 0
-		}
+	bi, ei, hm, tm := spanmasks(k, n)
+	if bi == ei {
+		m := hm & tm
+		d[bi] = d[bi]&^m | u&m
+		return
+	}
+	d[bi] = d[bi]&^hm | u&hm
+	for i := bi + 1; i < ei; i++ {
+		d[i] = u
+	}
+	if tm != 0 {
+		d[ei] = d[ei]&^tm | u&tm
+	}
+}
 
-		// move to the next dst bit
-		dsi++
-		if dsi >= 64 {
-			dbi++
-			dsi = 0
-		}
+// tglbits toggles n bits of d starting at bit position k, a block at a time.
+func tglbits(d []Bit, k, n int) {
+	if n <= 0 {
+		return
+	}
 
-		// one less bit to process
-		nb--
+	bi, ei, hm, tm := spanmasks(k, n)
+	if bi == ei {
+		d[bi] ^= hm & tm
+		return
+	}
+	d[bi] ^= hm
+	for i := bi + 1; i < ei; i++ {
+		d[i] = ^d[i]
 	}
+	if tm != 0 {
+		d[ei] ^= tm
+	}
+}
+
+// spanmasks returns the first and last blocks holding the n > 0 bits starting at bit
+// position k, and the masks of those bits in each. If the bits end on a block boundary,
+// the last block is the one after them and its mask is empty.
+func spanmasks(k, n int) (bi, ei int, hm, tm Bit) {
+	b, si := biandsi(k)
+	e, esi := biandsi(k + n)
+	return int(b), int(e), ^lomask(int(si)), lomask(int(esi))
 }
 
-func swapu64bits(n uint64, a, b *uint64) {
-	for i := uint64(0); n != 0; i++ {
-		abit := getbit(*a, i)
-		bbit := getbit(*b, i)
-		setbit(a, i, bbit)
-		setbit(b, i, abit)
-		n--
+// lomask returns a mask with the low n <= 64 bits set.
+func lomask(n int) Bit {
+	if n >= 64 {
+		return ^Bit(0)
 	}
+	return 1<<uint(n) - 1
 }
 
-func unalignedSwap(nb int, a []Bit, abi, asi uint64, b []Bit, bbi, bsi uint64) {
-	for nb != 0 {
-		anum, bnum := &a[abi], &b[bbi]
-		abit := getbit(*anum, asi)
-		bbit := getbit(*bnum, bsi)
-		setbit(anum, asi, bbit)
-		setbit(bnum, bsi, abit)
+// alignedCopy copies nb bits from the start of s to the start of d.
+func alignedCopy(nb int, d, s []Bit) {
+	m := nb / 64 // no. of whole blocks
+	copy(d[:m], s[:m])
+	if n := nb % 64; n != 0 {
+		mask := lomask(n)
+		d[m] = d[m]&^mask | s[m]&mask
+	}
+}
 
-		asi++
-		if asi >= 64 {
-			abi++
-			asi = 0
-		}
+// unalignedCopy copies nb bits starting at bit position si of the first block of s to the
+// start of d. Each block of d is funnel-shifted out of two consecutive blocks of s.
+func unalignedCopy(nb int, d, s []Bit, si uint64) {
+	i := 0
+	for ; nb >= 64; nb -= 64 {
+		d[i] = s[i]>>si | s[i+1]<<(64-si)
+		i++
+	}
+	if nb != 0 {
+		mask := lomask(nb)
+		d[i] = d[i]&^mask | getbits(s[i:], int(si), nb)
+	}
+}
 
-		bsi++
-		if bsi >= 64 {
-			bbi++
-			bsi = 0
-		}
+// alignedSwap swaps nb bits at the start of a with those at the start of b.
+func alignedSwap(nb int, a, b []Bit) {
+	m := nb / 64 // no. of whole blocks
+	for i := 0; i < m; i++ {
+		a[i], b[i] = b[i], a[i]
+	}
+	if n := nb % 64; n != 0 {
+		// only the bits that differ under the mask need flipping in both
+		x := (a[m] ^ b[m]) & lomask(n)
+		a[m] ^= x
+		b[m] ^= x
+	}
+}
 
-		nb--
+// unalignedSwap swaps nb bits at the start of a with those starting at bit position si of
+// the first block of b. Each block of a is exchanged with the bits straddling two
+// consecutive blocks of b.
+func unalignedSwap(nb int, a, b []Bit, si uint64) {
+	lo := lomask(int(si)) // the bits of b[i] below the ones being swapped
+	i := 0
+	for ; nb >= 64; nb -= 64 {
+		u := a[i]
+		a[i] = b[i]>>si | b[i+1]<<(64-si)
+		b[i] = b[i]&lo | u<<si
+		b[i+1] = b[i+1]&^lo | u>>(64-si)
+		i++
+	}
+	if nb != 0 {
+		k := 64*i + int(si)
+		u, v := a[i]&lomask(nb), getbits(b, k, nb)
+		a[i] = a[i]&^lomask(nb) | v
+		putbits(b, k, nb, u)
 	}
 }
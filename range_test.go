@@ -2,6 +2,7 @@ package bitarray
 
 import (
 	"math/rand"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -121,6 +122,32 @@ func TestSwapRange(t *testing.T) {
 		}
 	})
 
+	t.Run("all offsets and lengths", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+		oa, ob, a, b, ea, eb := New(400), New(400), New(400), New(400), New(400), New(400)
+		lens := []int{191, 192, 193, 255, 256, 257}
+		for n := 0; n <= 130; n++ {
+			lens = append(lens, n)
+		}
+		for ab := 0; ab < 128; ab++ {
+			randomize(&oa, rng)
+			randomize(&ob, rng)
+			for bb := 0; bb < 128; bb++ {
+				for _, n := range lens {
+					Copy(&a, &oa)
+					Copy(&b, &ob)
+					Copy(&ea, &oa)
+					Copy(&eb, &ob)
+					slowCopyRange(&ea, ab, &ob, bb, n)
+					slowCopyRange(&eb, bb, &oa, ab, n)
+					SwapRange(a.Range(ab, n+5), b.Range(bb, n))
+					if !slices.Equal(a.bits, ea.bits) || !slices.Equal(b.bits, eb.bits) {
+						t.Fatalf("Test SwapRange(%d, %d, %d) failed. got = %s\n%s\nexp = %s\n%s\n", ab, bb, n, a.String(), b.String(), ea.String(), eb.String())
+					}
+				}
+			}
+		}
+	})
 }
 
 func BenchmarkSwapRange(b *testing.B) {
@@ -191,6 +218,140 @@ func BenchmarkSwapRange(b *testing.B) {
 
 }
 
+func TestRangeView(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	ba := New(300)
+
+	t.Run("relative positions", func(t *testing.T) {
+		for _, tt := range [][2]int{{0, 300}, {0, 1}, {5, 59}, {63, 2}, {64, 64}, {70, 200}, {299, 1}} {
+			b, n := tt[0], tt[1]
+			randomize(&ba, rng)
+			exp := []byte(ba.String())
+			r := ba.Range(b, n)
+			for i := 0; i < 4*n; i++ {
+				k := rng.Intn(n)
+				switch rng.Intn(5) {
+				case 0:
+					r.Set(k)
+					exp[b+k] = '1'
+				case 1:
+					r.Clr(k)
+					exp[b+k] = '0'
+				case 2:
+					r.Tgl(k)
+					exp[b+k] ^= 1
+				case 3:
+					v := Bit(rng.Intn(2))
+					r.Put(k, v)
+					exp[b+k] = byte('0' + v)
+				case 4:
+					if r.Chk(k) != (exp[b+k] == '1') {
+						t.Fatalf("Test Chk(%d) of (%d, %d) failed. got = %t\n", k, b, n, r.Chk(k))
+					}
+				}
+			}
+			if got := ba.String(); got != string(exp) {
+				t.Fatalf("Test (%d, %d) failed. got = %s\nexp = %s\n", b, n, got, exp)
+			}
+			if got := r.String(); got != string(exp[b:b+n]) || r.Size() != n {
+				t.Fatalf("Test String (%d, %d) failed. got = %s\nexp = %s\n", b, n, got, exp[b:b+n])
+			}
+			if got, exp := r.Cnt(), strings.Count(string(exp[b:b+n]), "1"); got != exp {
+				t.Fatalf("Test Cnt (%d, %d) failed. got = %d, exp = %d\n", b, n, got, exp)
+			}
+			c := r.Clone()
+			if c.Size() != n || c.String() != r.String() {
+				t.Fatalf("Test Clone (%d, %d) failed. got = %s\nexp = %s\n", b, n, c.String(), r.String())
+			}
+			c.Tgl(0)
+			if c.String() == r.String() {
+				t.Fatalf("Test Clone (%d, %d) failed. the clone shares bits with the range\n", b, n)
+			}
+
+			r.SetAll()
+			es := string(exp[:b]) + strings.Repeat("1", n) + string(exp[b+n:])
+			if got := ba.String(); got != es {
+				t.Fatalf("Test SetAll (%d, %d) failed. got = %s\nexp = %s\n", b, n, got, es)
+			}
+			r.ClrAll()
+			es = string(exp[:b]) + strings.Repeat("0", n) + string(exp[b+n:])
+			if got := ba.String(); got != es {
+				t.Fatalf("Test ClrAll (%d, %d) failed. got = %s\nexp = %s\n", b, n, got, es)
+			}
+		}
+	})
+
+	t.Run("sub", func(t *testing.T) {
+		randomize(&ba, rng)
+		s := ba.Range(40, 200).Sub(30, 100)
+		if exp := ba.String()[70:170]; s.String() != exp {
+			t.Fatalf("Test failed. got = %s\nexp = %s\n", s.String(), exp)
+		}
+		s.Set(0)
+		if !ba.Chk(70) {
+			t.Fatalf("Test failed. bit %d is not set\n", 70)
+		}
+	})
+
+	t.Run("empty at the end", func(t *testing.T) {
+		for _, n := range []int{0, 10, 64, 128} {
+			a := New(n)
+			a.SetAll()
+			exp := a.String()
+			r := a.Range(n, 0)
+			if s := a.Range(0, n).Sub(n, 0); s != r {
+				t.Fatalf("Test %d failed. got = %+v, exp = %+v\n", n, s, r)
+			}
+			r.SetUint64(1)
+			r.AddUint64(1, OverflowWrap)
+			r.SetAll()
+			r.Fill(Zero)
+			r.Lsh(1)
+			r.RotL(1)
+			CopyRange(r, a.Range(0, n))
+			SwapRange(r, a.Range(0, n))
+			OrRange(r, a.Range(0, n))
+			if r.Uint64() != 0 || r.Int64() != 0 || r.Cnt() != 0 || r.String() != "" || !r.IsEmpty() || !r.IsFull() {
+				t.Fatalf("Test %d failed. the empty range holds bits\n", n)
+			}
+			if _, ok := r.NextSet(0); ok {
+				t.Fatalf("Test %d failed. found a set bit in an empty range\n", n)
+			}
+			if _, ok := r.PrevClr(0); ok {
+				t.Fatalf("Test %d failed. found a clear bit in an empty range\n", n)
+			}
+			for range r.All() {
+				t.Fatalf("Test %d failed. iterated over an empty range\n", n)
+			}
+			c := r.Clone()
+			a.AppendRange(r)
+			a.InsertRange(n/2, r)
+			if c.Size() != 0 || a.String() != exp {
+				t.Fatalf("Test %d failed. got = %s, exp = %s\n", n, a.String(), exp)
+			}
+		}
+	})
+
+	t.Run("bounds", func(t *testing.T) {
+		r := ba.Range(10, 20)
+		for name, fn := range map[string]func(){
+			"set past the end": func() { r.Set(20) },
+			"chk before start": func() { r.Chk(-1) },
+			"sub past the end": func() { r.Sub(15, 6) },
+			"negative sub":     func() { r.Sub(-1, 2) },
+		} {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Fatalf("Test %s failed. expected a panic\n", name)
+					}
+				}()
+				fn()
+			}()
+		}
+	})
+}
+
 func TestCopyRange(t *testing.T) {
 	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
 	t.Run("non-aligned copy < 64 bits", func(t *testing.T) {
@@ -242,6 +403,67 @@ func TestCopyRange(t *testing.T) {
 			t.Fatalf("Test CopyRange failed. got = %s\nexp=%s\n", dst.String(), es)
 		}
 	})
+
+	t.Run("all offsets and lengths", func(t *testing.T) {
+		src, orig, dst, exp := New(400), New(400), New(400), New(400)
+		randomize(&src, rng)
+		lens := []int{191, 192, 193, 255, 256, 257}
+		for n := 0; n <= 130; n++ {
+			lens = append(lens, n)
+		}
+		for db := 0; db < 128; db++ {
+			randomize(&orig, rng)
+			for sb := 0; sb < 128; sb++ {
+				for _, n := range lens {
+					Copy(&dst, &orig)
+					Copy(&exp, &orig)
+					slowCopyRange(&exp, db, &src, sb, n)
+					CopyRange(dst.Range(db, n), src.Range(sb, n+7))
+					if !slices.Equal(dst.bits, exp.bits) {
+						t.Fatalf("Test CopyRange(%d, %d, %d) failed. got = %s\nexp=%s\n", db, sb, n, dst.String(), exp.String())
+					}
+				}
+			}
+		}
+	})
+}
+
+func TestCopyRangeOverlap(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	orig, ba, exp := New(400), New(400), New(400)
+	for sb := 0; sb < 130; sb++ {
+		randomize(&orig, rng)
+		for db := 0; db < 130; db++ {
+			for _, n := range []int{1, 2, 3, 31, 63, 64, 65, 100, 127, 128, 129, 200, 257} {
+				Copy(&ba, &orig)
+				Copy(&exp, &orig)
+				slowCopyRange(&exp, db, &orig, sb, n)
+				CopyRange(ba.Range(db, n), ba.Range(sb, n))
+				if !slices.Equal(ba.bits, exp.bits) {
+					t.Fatalf("Test CopyRange(%d, %d, %d) failed. got = %s\nexp=%s\n", db, sb, n, ba.String(), exp.String())
+				}
+			}
+		}
+	}
+
+	t.Run("shift a field", func(t *testing.T) {
+		ba := FromStr("1101001110")
+		CopyRange(ba.Range(3, 7), ba.Range(0, 7))
+		if exp := "1101101001"; ba.String() != exp {
+			t.Fatalf("Test failed. got = %s, exp = %s\n", ba.String(), exp)
+		}
+	})
+}
+
+// slowCopyRange is the reference CopyRange, copying n bits one at a time.
+func slowCopyRange(dst *BitArray, db int, src *BitArray, sb, n int) {
+	for k := 0; k < n; k++ {
+		if src.Chk(sb + k) {
+			dst.Set(db + k)
+		} else {
+			dst.Clr(db + k)
+		}
+	}
 }
 
 func BenchmarkCopyRange(b *testing.B) {
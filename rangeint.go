@@ -0,0 +1,88 @@
+package bitarray
+
+// A Range of at most 64 bits can be read and written as an integer. The first bit of
+// the range is the least significant bit of the integer, the same order FromUint64 uses.
+
+// Overflow selects what AddUint64 does when the result does not fit in the range.
+type Overflow int
+
+const (
+	// OverflowWrap keeps the low bits of the result, as unsigned arithmetic does.
+	OverflowWrap Overflow = iota
+	// OverflowSat clamps the result to the smallest or largest value the range can hold.
+	OverflowSat
+	// OverflowFail leaves the range unchanged.
+	OverflowFail
+)
+
+// Uint64 returns the bits of the range as an unsigned integer.
+// It panics if the range is wider than 64 bits.
+func (r Range) Uint64() uint64 {
+	r.chkWidth()
+	if r.n == 0 {
+		return 0
+	}
+	return getbits(r.ba.bits, r.b, r.n)
+}
+
+// Int64 returns the bits of the range as a two's complement integer, sign-extended from
+// the last bit of the range. It panics if the range is wider than 64 bits.
+func (r Range) Int64() int64 {
+	sh := 64 - r.n
+	return int64(r.Uint64()<<sh) >> sh
+}
+
+// SetUint64 sets the bits of the range to the low bits of v.
+// It panics if the range is wider than 64 bits.
+func (r Range) SetUint64(v uint64) {
+	r.chkWidth()
+	if r.n == 0 {
+		return
+	}
+	putbits(r.ba.bits, r.b, r.n, v)
+}
+
+// AddUint64 adds delta to the unsigned integer held by the range and returns the new
+// value. If the result does not fit, the overflow mode decides what is stored and ok is
+// false. With OverflowFail the range is left unchanged and its current value returned.
+// It panics if the range is wider than 64 bits.
+func (r Range) AddUint64(delta int64, overflow Overflow) (v uint64, ok bool) {
+	v, hi := r.Uint64(), lomask(r.n)
+	if delta >= 0 {
+		d := uint64(delta)
+		if ok = d <= hi-v; ok {
+			v += d
+		} else {
+			switch overflow {
+			case OverflowWrap:
+				v = (v + d) & hi
+			case OverflowSat:
+				v = hi
+			case OverflowFail:
+				return v, false
+			}
+		}
+	} else {
+		d := uint64(-delta) // also correct for math.MinInt64
+		if ok = d <= v; ok {
+			v -= d
+		} else {
+			switch overflow {
+			case OverflowWrap:
+				v = (v - d) & hi
+			case OverflowSat:
+				v = 0
+			case OverflowFail:
+				return v, false
+			}
+		}
+	}
+	r.SetUint64(v)
+	return v, ok
+}
+
+func (r Range) chkWidth() {
+	if r.n > 64 {
+		panic("range is wider than 64 bits")
+	}
+}
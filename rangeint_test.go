@@ -0,0 +1,105 @@
+package bitarray
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRangeInt(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	ba := New(300)
+
+	t.Run("uint64", func(t *testing.T) {
+		for b := 0; b < 200; b += 7 {
+			for n := 1; n <= 64; n++ {
+				randomize(&ba, rng)
+				exp := ba.String()
+				r := ba.Range(b, n)
+
+				// the integer is the range read from its last bit to its first
+				var u uint64
+				for k := b + n - 1; k >= b; k-- {
+					u = u<<1 | uint64(exp[k]-'0')
+				}
+				if got := r.Uint64(); got != u {
+					t.Fatalf("Test Uint64(%d, %d) failed. got = %x, exp = %x\n", b, n, got, u)
+				}
+				if sh := 64 - n; r.Int64() != int64(u<<sh)>>sh {
+					t.Fatalf("Test Int64(%d, %d) failed. got = %d, exp = %d\n", b, n, r.Int64(), int64(u<<sh)>>sh)
+				}
+
+				v := rng.Uint64()
+				r.SetUint64(v)
+				var sb strings.Builder
+				for k := 0; k < n; k++ {
+					sb.WriteByte(byte('0' + v>>k&1))
+				}
+				exp = exp[:b] + sb.String() + exp[b+n:]
+				if got := ba.String(); got != exp {
+					t.Fatalf("Test SetUint64(%d, %d) failed. got = %s\nexp = %s\n", b, n, got, exp)
+				}
+			}
+		}
+	})
+
+	t.Run("int64", func(t *testing.T) {
+		r := ba.Range(61, 5)
+		for _, v := range []int64{-16, -1, 0, 1, 15} {
+			r.SetUint64(uint64(v))
+			if got := r.Int64(); got != v {
+				t.Fatalf("Test failed. got = %d, exp = %d\n", got, v)
+			}
+		}
+	})
+
+	t.Run("add", func(t *testing.T) {
+		for _, tt := range []struct {
+			n     int
+			v     uint64
+			delta int64
+			mode  Overflow
+			exp   uint64
+			ok    bool
+		}{
+			{8, 100, 27, OverflowWrap, 127, true},
+			{8, 100, -100, OverflowFail, 0, true},
+			{8, 250, 10, OverflowWrap, 4, false},
+			{8, 250, 10, OverflowSat, 255, false},
+			{8, 250, 10, OverflowFail, 250, false},
+			{8, 5, -10, OverflowWrap, 251, false},
+			{8, 5, -10, OverflowSat, 0, false},
+			{8, 5, -10, OverflowFail, 5, false},
+			{8, 5, math.MinInt64, OverflowWrap, 5, false},
+			{3, 7, 1, OverflowWrap, 0, false},
+			{64, math.MaxUint64, 1, OverflowWrap, 0, false},
+			{64, math.MaxUint64, 1, OverflowSat, math.MaxUint64, false},
+			{64, 0, math.MinInt64, OverflowWrap, 1 << 63, false},
+			{64, 1 << 63, math.MinInt64, OverflowFail, 0, true},
+		} {
+			randomize(&ba, rng)
+			before := ba.String()
+			r := ba.Range(93, tt.n)
+			r.SetUint64(tt.v)
+			v, ok := r.AddUint64(tt.delta, tt.mode)
+			if v != tt.exp || ok != tt.ok || r.Uint64() != tt.exp {
+				t.Fatalf("Test %+v failed. got = %d, %t, stored %d\n", tt, v, ok, r.Uint64())
+			}
+			// bits around the range are untouched
+			if got := ba.String(); got[:93] != before[:93] || got[93+tt.n:] != before[93+tt.n:] {
+				t.Fatalf("Test %+v failed. got = %s\nexp = %s\n", tt, got, before)
+			}
+		}
+	})
+
+	t.Run("too wide", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("Test failed. expected a panic\n")
+			}
+		}()
+		ba.Range(0, 65).Uint64()
+	})
+}
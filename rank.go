@@ -0,0 +1,249 @@
+package bitarray
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// RankSelect is an auxiliary index over a BitArray that answers rank and select queries
+// in constant time or close to it.
+//
+// The bits are split into superblocks of 512 bits. For each superblock the index stores the
+// number of set bits before it and, packed 9 bits apiece into a second word, the number of
+// set bits before each of its 8 blocks. Select queries are narrowed down by a sample of the
+// superblock holding every 8192th set (or clear) bit. The whole index costs a little over 25%
+// of the size of the array, see Overhead.
+//
+// The index is a snapshot of the bits at the time it was built. Mutations made through the
+// index (Set, Clr, Put, Tgl, ChkSet, ChkClr, Swap and the range and whole-array fills) mark
+// it stale and it is rebuilt by the next query, as is a change in the size of the BitArray.
+// Other changes made directly on the BitArray or through its Ranges are not tracked: after
+// setting or clearing its bits without changing its size, the index returns wrong answers
+// until Invalidate or Rebuild is called.
+type RankSelect struct {
+	ba *BitArray
+
+	// rank holds two words per superblock: the no. of set bits before the superblock and the
+	// 9-bit counts of set bits before each of its blocks 1..7. There is an extra superblock at
+	// the end so that Rank1(Size()) needs no special case.
+	rank []uint64
+
+	// sel1 and sel0 hold the superblock containing every selGap'th set and clear bit respectively.
+	sel1, sel0 []uint32
+
+	ones  int
+	n     int // size of the BitArray when the index was built
+	nblk  int // no. of blocks of the BitArray when the index was built
+	stale bool
+}
+
+const (
+	sbBits = 512      // bits per superblock
+	sbBlks = 512 / 64 // blocks per superblock
+	selGap = 8192     // set or clear bits between select samples
+)
+
+// NewRankSelect builds a rank/select index over ba.
+func NewRankSelect(ba *BitArray) *RankSelect {
+	x := &RankSelect{ba: ba}
+	x.Rebuild()
+	return x
+}
+
+// Rebuild rebuilds the index from the current contents of the underlying BitArray.
+func (x *RankSelect) Rebuild() {
+	ba := x.ba
+	nsb := len(ba.bits)/sbBlks + 1
+	x.rank = x.rank[:0]
+	x.sel1 = x.sel1[:0]
+	x.sel0 = x.sel0[:0]
+
+	var c1 uint64
+	for s := 0; s < nsb; s++ {
+		var rel, c uint64
+		for w := 0; w < sbBlks; w++ {
+			if w > 0 {
+				rel |= c << (9 * (w - 1))
+			}
+			c += uint64(bits.OnesCount64(ba.blk(s*sbBlks + w)))
+		}
+		x.rank = append(x.rank, c1, rel)
+
+		// sample the superblock containing every selGap'th set and clear bit
+		c0 := uint64(s*sbBits) - c1
+		for uint64(len(x.sel1))*selGap < c1+c {
+			x.sel1 = append(x.sel1, uint32(s))
+		}
+		for uint64(len(x.sel0))*selGap < c0+sbBits-c {
+			x.sel0 = append(x.sel0, uint32(s))
+		}
+		c1 += c
+	}
+	x.ones = int(c1)
+	x.n, x.nblk = ba.n, len(ba.bits)
+	x.stale = false
+}
+
+// Invalidate marks the index stale, so that the next query rebuilds it.
+func (x *RankSelect) Invalidate() { x.stale = true }
+
+// Set sets the bit at position k of the underlying BitArray.
+func (x *RankSelect) Set(k int) { x.ba.Set(k); x.stale = true }
+
+// Clr clears the bit at position k of the underlying BitArray.
+func (x *RankSelect) Clr(k int) { x.ba.Clr(k); x.stale = true }
+
+// Put sets the value of the bit at position k of the underlying BitArray to v.
+func (x *RankSelect) Put(k int, v Bit) { x.ba.Put(k, v); x.stale = true }
+
+// Tgl toggles the bit at position k of the underlying BitArray.
+func (x *RankSelect) Tgl(k int) { x.ba.Tgl(k); x.stale = true }
+
+// ChkSet returns the value of the bit at position k of the underlying BitArray before setting it.
+func (x *RankSelect) ChkSet(k int) bool { x.stale = true; return x.ba.ChkSet(k) }
+
+// ChkClr returns the value of the bit at position k of the underlying BitArray before clearing it.
+func (x *RankSelect) ChkClr(k int) bool { x.stale = true; return x.ba.ChkClr(k) }
+
+// Swap swaps the value of the bit at position k of the underlying BitArray with v.
+// On return, v contains the old value.
+func (x *RankSelect) Swap(k int, v *Bit) { x.ba.Swap(k, v); x.stale = true }
+
+// SetRange sets the n bits of the underlying BitArray starting at position b.
+func (x *RankSelect) SetRange(b, n int) { x.ba.SetRange(b, n); x.stale = true }
+
+// ClrRange clears the n bits of the underlying BitArray starting at position b.
+func (x *RankSelect) ClrRange(b, n int) { x.ba.ClrRange(b, n); x.stale = true }
+
+// TglRange toggles the n bits of the underlying BitArray starting at position b.
+func (x *RankSelect) TglRange(b, n int) { x.ba.TglRange(b, n); x.stale = true }
+
+// PutRange sets the n bits of the underlying BitArray starting at position b to v.
+func (x *RankSelect) PutRange(b, n int, v Bit) { x.ba.PutRange(b, n, v); x.stale = true }
+
+// SetAll sets all the bits of the underlying BitArray.
+func (x *RankSelect) SetAll() { x.ba.SetAll(); x.stale = true }
+
+// ClrAll clears all the bits of the underlying BitArray.
+func (x *RankSelect) ClrAll() { x.ba.ClrAll(); x.stale = true }
+
+// Overhead returns the no. of bits used by the index, not counting the BitArray itself.
+func (x *RankSelect) Overhead() int {
+	return 64*len(x.rank) + 32*(len(x.sel1)+len(x.sel0))
+}
+
+// Cnt returns the number of set bits.
+func (x *RankSelect) Cnt() int {
+	x.sync()
+	return x.ones
+}
+
+// Rank1 returns the number of set bits before position i.
+func (x *RankSelect) Rank1(i int) int {
+	x.sync()
+	i = max(0, min(i, x.ba.n))
+	s, w := i/sbBits, i/64%sbBlks
+	r := x.rank[2*s] + relcnt(x.rank[2*s+1], w)
+	if si := i % 64; si != 0 {
+		r += uint64(bits.OnesCount64(x.ba.bits[i/64] & lomask(si)))
+	}
+	return int(r)
+}
+
+// Rank0 returns the number of clear bits before position i.
+func (x *RankSelect) Rank0(i int) int {
+	x.sync()
+	i = max(0, min(i, x.ba.n))
+	return i - x.Rank1(i)
+}
+
+// Select1 returns the position of the k'th set bit, counting from 0.
+// ok is false if there are not more than k set bits.
+func (x *RankSelect) Select1(k int) (int, bool) {
+	x.sync()
+	if k < 0 || k >= x.ones {
+		return -1, false
+	}
+	return x.sel(k, x.sel1, false), true
+}
+
+// Select0 returns the position of the k'th clear bit, counting from 0.
+// ok is false if there are not more than k clear bits.
+func (x *RankSelect) Select0(k int) (int, bool) {
+	x.sync()
+	if k < 0 || k >= x.ba.n-x.ones {
+		return -1, false
+	}
+	return x.sel(k, x.sel0, true), true
+}
+
+// sync rebuilds the index if it is stale or the size of the BitArray changed.
+func (x *RankSelect) sync() {
+	if x.stale || x.n != x.ba.n || x.nblk != len(x.ba.bits) {
+		x.Rebuild()
+	}
+}
+
+// sel returns the position of the k'th set bit, or clear bit if zeros is true.
+func (x *RankSelect) sel(k int, samples []uint32, zeros bool) int {
+	// cnt returns the no. of bits of interest before superblock s
+	cnt := func(s int) int {
+		c := int(x.rank[2*s])
+		if zeros {
+			c = s*sbBits - c
+		}
+		return c
+	}
+
+	// find the last superblock, between the two samples around k, starting at or before k
+	lo, hi := int(samples[k/selGap]), len(x.rank)/2-1
+	if j := k/selGap + 1; j < len(samples) {
+		hi = int(samples[j])
+	}
+	s := lo + sort.Search(hi-lo+1, func(i int) bool { return cnt(lo+i) > k }) - 1
+	r := k - cnt(s)
+
+	// find the block within the superblock
+	rel, w := x.rank[2*s+1], sbBlks-1
+	for ; w > 0; w-- {
+		c := int(relcnt(rel, w))
+		if zeros {
+			c = w*64 - c
+		}
+		if c <= r {
+			r -= c
+			break
+		}
+	}
+
+	u := x.ba.blk(s*sbBlks + w)
+	if zeros {
+		u = ^u
+	}
+	return (s*sbBlks+w)*64 + selectu64(u, r)
+}
+
+// relcnt returns the no. of set bits before block w of a superblock from its packed counts.
+func relcnt(rel uint64, w int) uint64 {
+	if w == 0 {
+		return 0
+	}
+	return rel >> (9 * (w - 1)) & 0x1ff
+}
+
+// selectu64 returns the position of the r'th set bit of u, counting from 0.
+func selectu64(u uint64, r int) int {
+	k := 0
+	for ; ; k += 8 {
+		c := bits.OnesCount8(uint8(u >> k))
+		if r < c {
+			break
+		}
+		r -= c
+	}
+	b := uint8(u >> k)
+	for ; r > 0; r-- {
+		b &= b - 1
+	}
+	return k + bits.TrailingZeros8(b)
+}
@@ -0,0 +1,190 @@
+package bitarray
+
+import (
+	"math/bits"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestRankSelect(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for _, n := range []int{0, 1, 63, 64, 65, 511, 512, 513, 1500, 40000} {
+		for _, density := range []float32{0, 0.001, 0.1, 0.5, 0.9, 1} {
+			ba := New(n)
+			for k := 0; k < n; k++ {
+				if rng.Float32() < density {
+					ba.Set(k)
+				}
+			}
+			x := NewRankSelect(&ba)
+
+			var r1 int
+			var pos1, pos0 []int
+			for i := 0; i <= n; i++ {
+				if got := x.Rank1(i); got != r1 {
+					t.Fatalf("Test Rank1(%d) of %d bits failed. got = %d, exp = %d\n", i, n, got, r1)
+				}
+				if got := x.Rank0(i); got != i-r1 {
+					t.Fatalf("Test Rank0(%d) of %d bits failed. got = %d, exp = %d\n", i, n, got, i-r1)
+				}
+				if i < n {
+					if ba.Chk(i) {
+						r1++
+						pos1 = append(pos1, i)
+					} else {
+						pos0 = append(pos0, i)
+					}
+				}
+			}
+
+			if x.Cnt() != len(pos1) {
+				t.Fatalf("Test Cnt of %d bits failed. got = %d, exp = %d\n", n, x.Cnt(), len(pos1))
+			}
+			for k := -1; k <= len(pos1); k++ {
+				got, ok := x.Select1(k)
+				if ok != (k >= 0 && k < len(pos1)) || (ok && got != pos1[k]) {
+					t.Fatalf("Test Select1(%d) of %d bits failed. got = %d, %t\n", k, n, got, ok)
+				}
+			}
+			for k := -1; k <= len(pos0); k++ {
+				got, ok := x.Select0(k)
+				if ok != (k >= 0 && k < len(pos0)) || (ok && got != pos0[k]) {
+					t.Fatalf("Test Select0(%d) of %d bits failed. got = %d, %t\n", k, n, got, ok)
+				}
+			}
+		}
+	}
+
+	t.Run("mutation", func(t *testing.T) {
+		ba := New(1000)
+		x := NewRankSelect(&ba)
+		x.Set(10)
+		x.Set(700)
+		if got := x.Rank1(701); got != 2 {
+			t.Fatalf("Test failed. got = %d, exp = %d\n", got, 2)
+		}
+		x.Clr(10)
+		if got, _ := x.Select1(0); got != 700 {
+			t.Fatalf("Test failed. got = %d, exp = %d\n", got, 700)
+		}
+
+		ba.Set(5)
+		x.Invalidate()
+		if got, _ := x.Select1(0); got != 5 {
+			t.Fatalf("Test failed. got = %d, exp = %d\n", got, 5)
+		}
+
+		// every mutator of the index, each followed by a query
+		for _, m := range []struct {
+			name string
+			fn   func()
+		}{
+			{"tgl", func() { x.Tgl(20) }},
+			{"chkset", func() { x.ChkSet(30) }},
+			{"chkclr", func() { x.ChkClr(5) }},
+			{"swap", func() { v := One; x.Swap(40, &v) }},
+			{"setrange", func() { x.SetRange(100, 300) }},
+			{"clrrange", func() { x.ClrRange(150, 20) }},
+			{"tglrange", func() { x.TglRange(390, 100) }},
+			{"putrange", func() { x.PutRange(600, 10, One) }},
+			{"clrall", func() { x.ClrAll() }},
+			{"setall", func() { x.SetAll() }},
+		} {
+			m.fn()
+			if got, exp := x.Rank1(ba.n), ba.Cnt(); got != exp {
+				t.Fatalf("Test %s failed. got = %d, exp = %d\n", m.name, got, exp)
+			}
+			if got, exp := x.Rank1(500), ba.Range(0, 500).Cnt(); got != exp {
+				t.Fatalf("Test %s failed. got = %d, exp = %d\n", m.name, got, exp)
+			}
+		}
+	})
+
+	t.Run("resize", func(t *testing.T) {
+		ba := New(100)
+		ba.Set(3)
+		x := NewRankSelect(&ba)
+		for i := 0; i < 2000; i++ {
+			ba.Append(One)
+		}
+		if got := x.Rank1(ba.Size()); got != 2001 {
+			t.Fatalf("Test failed. got = %d, exp = %d\n", got, 2001)
+		}
+		if got, ok := x.Select0(98); !ok || got != 99 {
+			t.Fatalf("Test failed. got = %d, %t, exp = %d\n", got, ok, 99)
+		}
+		if got, ok := x.Select0(99); ok {
+			t.Fatalf("Test failed. got = %d, exp no 100th clear bit\n", got)
+		}
+
+		// a change within the last block
+		ba.Truncate(2050)
+		if got := x.Cnt(); got != 1951 {
+			t.Fatalf("Test failed. got = %d, exp = %d\n", got, 1951)
+		}
+		ba.Append(Zero)
+		if got := x.Rank0(ba.Size()); got != 100 {
+			t.Fatalf("Test failed. got = %d, exp = %d\n", got, 100)
+		}
+	})
+
+	t.Run("overhead", func(t *testing.T) {
+		ba := New(1 << 20)
+		randomize(&ba, rng)
+		x := NewRankSelect(&ba)
+		if o := x.Overhead(); o > ba.n*26/100 {
+			t.Fatalf("Test failed. overhead of %d bits for %d bits\n", o, ba.n)
+		}
+	})
+}
+
+func TestSelectU64(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for i := 0; i < 1000; i++ {
+		u := rng.Uint64()
+		r := 0
+		for k := 0; k < 64; k++ {
+			if u>>k&1 == 0 {
+				continue
+			}
+			if got := selectu64(u, r); got != k {
+				t.Fatalf("Test selectu64(%x, %d) failed. got = %d, exp = %d\n", u, r, got, k)
+			}
+			r++
+		}
+		if r != bits.OnesCount64(u) {
+			t.Fatalf("Test failed. got = %d, exp = %d\n", r, bits.OnesCount64(u))
+		}
+	}
+}
+
+func BenchmarkRankSelect(b *testing.B) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	ba := New(1 << 20)
+	randomize(&ba, rng)
+	x := NewRankSelect(&ba)
+	ones := x.Cnt()
+
+	b.Run("rank1", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			x.Rank1(i & (ba.n - 1))
+		}
+	})
+
+	b.Run("select1", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			x.Select1(i % ones)
+		}
+	})
+
+	b.Run("build", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			x.Rebuild()
+		}
+	})
+}
@@ -0,0 +1,659 @@
+package bitarray
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"iter"
+	"slices"
+)
+
+// Roaring is a compressed set of uint32s. The values are split by their high 16 bits into
+// chunks, and the low 16 bits of the values of each chunk are stored in a container that
+// suits their density:
+//
+//   - an array container holds up to 4096 values as a sorted []uint16,
+//   - a bitmap container holds more than 4096 values in a BitArray of 65536 bits,
+//   - a run container holds the values as a sorted list of runs of consecutive values.
+//
+// Run containers are only created by RunOptimize and by reading a serialized bitmap.
+// The zero value is an empty set ready to use.
+//
+// WriteTo and ReadFrom use the portable Roaring serialization format, shared with the
+// Java, C and Go implementations: https://github.com/RoaringBitmap/RoaringFormatSpec.
+type Roaring struct {
+	keys []uint16 // high 16 bits of the values of each container, sorted
+	cs   []*container
+}
+
+const (
+	arrayMax     = 4096
+	bitmapBits   = 1 << 16
+	bitmapBytes  = bitmapBits / 8
+	cookieNoRuns = 12346
+	cookieRuns   = 12347
+	noOffsetMax  = 4 // containers below which a bitmap with runs has no offset header
+)
+
+const (
+	arrayKind = iota
+	bitmapKind
+	runKind
+)
+
+// The set operations on containers.
+const (
+	opAnd = iota
+	opOr
+	opXor
+	opAndNot
+)
+
+type container struct {
+	kind uint8
+	card int
+	arr  []uint16
+	bm   BitArray
+	runs []run16
+}
+
+// run16 is a run of consecutive values, both ends inclusive.
+type run16 struct{ first, last uint16 }
+
+// Add adds x to the set.
+func (r *Roaring) Add(x uint32) {
+	hi, lo := uint16(x>>16), uint16(x)
+	i, ok := slices.BinarySearch(r.keys, hi)
+	if !ok {
+		r.keys = slices.Insert(r.keys, i, hi)
+		r.cs = slices.Insert(r.cs, i, &container{})
+	}
+	r.cs[i].add(lo)
+}
+
+// Remove removes x from the set.
+func (r *Roaring) Remove(x uint32) {
+	hi, lo := uint16(x>>16), uint16(x)
+	i, ok := slices.BinarySearch(r.keys, hi)
+	if !ok {
+		return
+	}
+	c := r.cs[i]
+	c.remove(lo)
+	if c.card == 0 {
+		r.keys = slices.Delete(r.keys, i, i+1)
+		r.cs = slices.Delete(r.cs, i, i+1)
+	}
+}
+
+// Contains reports whether x is in the set.
+func (r *Roaring) Contains(x uint32) bool {
+	i, ok := slices.BinarySearch(r.keys, uint16(x>>16))
+	return ok && r.cs[i].contains(uint16(x))
+}
+
+// Cnt returns the number of values in the set.
+func (r *Roaring) Cnt() (n int) {
+	for _, c := range r.cs {
+		n += c.card
+	}
+	return
+}
+
+// Ones returns an iterator over the values in the set, in increasing order.
+func (r *Roaring) Ones() iter.Seq[uint32] {
+	return func(yield func(uint32) bool) {
+		for i, c := range r.cs {
+			hi := uint32(r.keys[i]) << 16
+			if !c.each(func(lo uint16) bool { return yield(hi | uint32(lo)) }) {
+				return
+			}
+		}
+	}
+}
+
+// And returns the intersection of r and o.
+func (r *Roaring) And(o *Roaring) *Roaring { return roaringop(r, o, opAnd) }
+
+// Or returns the union of r and o.
+func (r *Roaring) Or(o *Roaring) *Roaring { return roaringop(r, o, opOr) }
+
+// Xor returns the symmetric difference of r and o.
+func (r *Roaring) Xor(o *Roaring) *Roaring { return roaringop(r, o, opXor) }
+
+// AndNot returns the values of r that are not in o.
+func (r *Roaring) AndNot(o *Roaring) *Roaring { return roaringop(r, o, opAndNot) }
+
+// RunOptimize converts every container to a run container if that takes less space,
+// and run containers back to array or bitmap containers if it does not.
+func (r *Roaring) RunOptimize() {
+	for _, c := range r.cs {
+		c.optimize()
+	}
+}
+
+func roaringop(a, b *Roaring, op int) *Roaring {
+	r := &Roaring{}
+	push := func(k uint16, c *container) {
+		if c.card != 0 {
+			r.keys = append(r.keys, k)
+			r.cs = append(r.cs, c)
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a.keys) || j < len(b.keys) {
+		switch {
+		case j >= len(b.keys) || (i < len(a.keys) && a.keys[i] < b.keys[j]):
+			if op != opAnd {
+				push(a.keys[i], a.cs[i].clone())
+			}
+			i++
+		case i >= len(a.keys) || b.keys[j] < a.keys[i]:
+			if op == opOr || op == opXor {
+				push(b.keys[j], b.cs[j].clone())
+			}
+			j++
+		default:
+			push(a.keys[i], cop(a.cs[i], b.cs[j], op))
+			i++
+			j++
+		}
+	}
+	return r
+}
+
+func (c *container) contains(v uint16) bool {
+	switch c.kind {
+	case arrayKind:
+		_, ok := slices.BinarySearch(c.arr, v)
+		return ok
+	case bitmapKind:
+		return c.bm.Chk(int(v))
+	}
+	i := c.findRun(v)
+	return i < len(c.runs) && c.runs[i].first <= v
+}
+
+// findRun returns the index of the first run that ends at or after v.
+func (c *container) findRun(v uint16) int {
+	i, _ := slices.BinarySearchFunc(c.runs, v, func(r run16, v uint16) int { return int(r.last) - int(v) })
+	return i
+}
+
+func (c *container) add(v uint16) {
+	switch c.kind {
+	case runKind:
+		if c.contains(v) {
+			return
+		}
+		c.unrun()
+		c.add(v)
+	case arrayKind:
+		i, ok := slices.BinarySearch(c.arr, v)
+		if ok {
+			return
+		}
+		c.arr = slices.Insert(c.arr, i, v)
+		c.card++
+		if c.card > arrayMax {
+			c.toBitmap()
+		}
+	case bitmapKind:
+		if !c.bm.ChkSet(int(v)) {
+			c.card++
+		}
+	}
+}
+
+func (c *container) remove(v uint16) {
+	switch c.kind {
+	case runKind:
+		if !c.contains(v) {
+			return
+		}
+		c.unrun()
+		c.remove(v)
+	case arrayKind:
+		if i, ok := slices.BinarySearch(c.arr, v); ok {
+			c.arr = slices.Delete(c.arr, i, i+1)
+			c.card--
+		}
+	case bitmapKind:
+		if c.bm.ChkClr(int(v)) {
+			c.card--
+			if c.card <= arrayMax {
+				c.toArray()
+			}
+		}
+	}
+}
+
+// each calls yield for every value in the container, in increasing order,
+// stopping early if yield returns false. It reports whether it went through all the values.
+func (c *container) each(yield func(uint16) bool) bool {
+	switch c.kind {
+	case arrayKind:
+		for _, v := range c.arr {
+			if !yield(v) {
+				return false
+			}
+		}
+	case bitmapKind:
+		for k := range c.bm.Ones() {
+			if !yield(uint16(k)) {
+				return false
+			}
+		}
+	case runKind:
+		for _, r := range c.runs {
+			for v := int(r.first); v <= int(r.last); v++ {
+				if !yield(uint16(v)) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+func (c *container) clone() *container {
+	d := &container{kind: c.kind, card: c.card, arr: slices.Clone(c.arr), runs: slices.Clone(c.runs)}
+	if c.kind == bitmapKind {
+		d.bm = New(bitmapBits)
+		Copy(&d.bm, &c.bm)
+	}
+	return d
+}
+
+// bitmap returns the values of the container as a bitmap. The result may be c's own bitmap.
+func (c *container) bitmap() *BitArray {
+	if c.kind == bitmapKind {
+		return &c.bm
+	}
+	ba := New(bitmapBits)
+	switch c.kind {
+	case arrayKind:
+		for _, v := range c.arr {
+			ba.Set(int(v))
+		}
+	case runKind:
+		for _, r := range c.runs {
+			fillbits(ba.bits, int(r.first), int(r.last)-int(r.first)+1, One)
+		}
+	}
+	return &ba
+}
+
+func (c *container) toBitmap() {
+	c.bm = *c.bitmap()
+	c.kind, c.arr, c.runs = bitmapKind, nil, nil
+}
+
+func (c *container) toArray() {
+	arr := make([]uint16, 0, c.card)
+	c.each(func(v uint16) bool { arr = append(arr, v); return true })
+	c.kind, c.arr, c.bm, c.runs = arrayKind, arr, BitArray{}, nil
+}
+
+// unrun converts a run container to an array or a bitmap container, whichever fits its cardinality.
+func (c *container) unrun() {
+	if c.card <= arrayMax {
+		c.toArray()
+	} else {
+		c.toBitmap()
+	}
+}
+
+// normalize converts a bitmap or array container to the kind that fits its cardinality.
+func (c *container) normalize() {
+	switch {
+	case c.kind == bitmapKind && c.card <= arrayMax:
+		c.toArray()
+	case c.kind == arrayKind && c.card > arrayMax:
+		c.toBitmap()
+	}
+}
+
+func (c *container) optimize() {
+	var runs []run16
+	c.each(func(v uint16) bool {
+		if n := len(runs); n != 0 && int(runs[n-1].last)+1 == int(v) {
+			runs[n-1].last = v
+		} else {
+			runs = append(runs, run16{v, v})
+		}
+		return true
+	})
+
+	size := bitmapBytes
+	if c.card <= arrayMax {
+		size = 2 * c.card
+	}
+	switch {
+	case 2+4*len(runs) < size:
+		c.kind, c.arr, c.bm, c.runs = runKind, nil, BitArray{}, runs
+	case c.kind == runKind:
+		c.unrun()
+	}
+}
+
+// cop combines the containers a and b with the set operation op into a new container.
+func cop(a, b *container, op int) *container {
+	if a.kind == arrayKind && b.kind == arrayKind {
+		c := &container{kind: arrayKind, arr: mergeArrays(a.arr, b.arr, op)}
+		c.card = len(c.arr)
+		c.normalize()
+		return c
+	}
+
+	// filter the values of an array container with the other container
+	filter := func(arr []uint16, o *container, keep bool) *container {
+		c := &container{kind: arrayKind}
+		for _, v := range arr {
+			if o.contains(v) == keep {
+				c.arr = append(c.arr, v)
+			}
+		}
+		c.card = len(c.arr)
+		return c
+	}
+	switch {
+	case op == opAnd && a.kind == arrayKind:
+		return filter(a.arr, b, true)
+	case op == opAnd && b.kind == arrayKind:
+		return filter(b.arr, a, true)
+	case op == opAndNot && a.kind == arrayKind:
+		return filter(a.arr, b, false)
+	}
+
+	c := &container{kind: bitmapKind, bm: New(bitmapBits)}
+	Copy(&c.bm, a.bitmap())
+	y := b.bitmap()
+	switch op {
+	case opAnd:
+		c.bm.And(y)
+	case opOr:
+		c.bm.Or(y)
+	case opXor:
+		c.bm.Xor(y)
+	case opAndNot:
+		c.bm.AndNot(y)
+	}
+	c.card = c.bm.Cnt()
+	c.normalize()
+	return c
+}
+
+// mergeArrays combines the sorted arrays a and b with the set operation op.
+func mergeArrays(a, b []uint16, op int) []uint16 {
+	var r []uint16
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j >= len(b) || (i < len(a) && a[i] < b[j]):
+			if op != opAnd {
+				r = append(r, a[i])
+			}
+			i++
+		case i >= len(a) || b[j] < a[i]:
+			if op == opOr || op == opXor {
+				r = append(r, b[j])
+			}
+			j++
+		default:
+			if op == opAnd || op == opOr {
+				r = append(r, a[i])
+			}
+			i++
+			j++
+		}
+	}
+	return r
+}
+
+// serialKind returns the kind a container is serialized as, which the reader infers
+// from the run flags and the cardinality.
+func (c *container) serialKind() uint8 {
+	switch {
+	case c.kind == runKind:
+		return runKind
+	case c.card <= arrayMax:
+		return arrayKind
+	}
+	return bitmapKind
+}
+
+func (c *container) serialSize() int {
+	switch c.serialKind() {
+	case runKind:
+		return 2 + 4*len(c.runs)
+	case arrayKind:
+		return 2 * c.card
+	}
+	return bitmapBytes
+}
+
+func (c *container) appendTo(b []byte) []byte {
+	le := binary.LittleEndian
+	switch c.serialKind() {
+	case runKind:
+		b = le.AppendUint16(b, uint16(len(c.runs)))
+		for _, r := range c.runs {
+			b = le.AppendUint16(b, r.first)
+			b = le.AppendUint16(b, r.last-r.first)
+		}
+	case arrayKind:
+		c.each(func(v uint16) bool { b = le.AppendUint16(b, v); return true })
+	case bitmapKind:
+		bm := c.bitmap()
+		for _, u := range bm.bits {
+			b = le.AppendUint64(b, u)
+		}
+	}
+	return b
+}
+
+// WriteTo implements the io.WriterTo interface. It writes the set to w in the portable
+// Roaring serialization format.
+func (r *Roaring) WriteTo(w io.Writer) (int64, error) {
+	le := binary.LittleEndian
+	n := len(r.cs)
+	hasRuns := slices.ContainsFunc(r.cs, func(c *container) bool { return c.kind == runKind })
+
+	var b []byte
+	if hasRuns {
+		b = le.AppendUint32(b, cookieRuns|uint32(n-1)<<16)
+		flags := make([]byte, (n+7)/8)
+		for i, c := range r.cs {
+			if c.kind == runKind {
+				flags[i/8] |= 1 << (i % 8)
+			}
+		}
+		b = append(b, flags...)
+	} else {
+		b = le.AppendUint32(b, cookieNoRuns)
+		b = le.AppendUint32(b, uint32(n))
+	}
+	for i, c := range r.cs {
+		b = le.AppendUint16(b, r.keys[i])
+		b = le.AppendUint16(b, uint16(c.card-1))
+	}
+	if !hasRuns || n >= noOffsetMax {
+		off := len(b) + 4*n
+		for _, c := range r.cs {
+			b = le.AppendUint32(b, uint32(off))
+			off += c.serialSize()
+		}
+	}
+
+	k, err := w.Write(b)
+	nw := int64(k)
+	if err != nil {
+		return nw, err
+	}
+	for _, c := range r.cs {
+		b = c.appendTo(b[:0])
+		k, err = w.Write(b)
+		nw += int64(k)
+		if err != nil {
+			return nw, err
+		}
+	}
+	return nw, nil
+}
+
+// ReadFrom implements the io.ReaderFrom interface. It replaces the contents of the set with
+// a set read from r in the portable Roaring serialization format. It reads exactly one set,
+// leaving any data that follows it in r. Problems with the data are reported as a *FormatError.
+func (r *Roaring) ReadFrom(rd io.Reader) (int64, error) {
+	le := binary.LittleEndian
+	var nr int64
+	buf := make([]byte, bitmapBytes)
+	read := func(n int) ([]byte, error) {
+		if n > len(buf) {
+			buf = make([]byte, n)
+		}
+		k, err := io.ReadFull(rd, buf[:n])
+		nr += int64(k)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, &FormatError{nr, ErrTruncated}
+		}
+		return buf[:n], err
+	}
+
+	b, err := read(4)
+	if err != nil {
+		return nr, err
+	}
+	var n int
+	var runs []byte
+	switch cookie := le.Uint32(b); {
+	case cookie == cookieNoRuns:
+		if b, err = read(4); err != nil {
+			return nr, err
+		}
+		if n = int(le.Uint32(b)); n > bitmapBits {
+			return nr, &FormatError{4, ErrCorrupt}
+		}
+	case cookie&0xffff == cookieRuns:
+		n = int(cookie>>16) + 1
+		if b, err = read((n + 7) / 8); err != nil {
+			return nr, err
+		}
+		runs = bytes.Clone(b)
+	default:
+		return nr, &FormatError{0, ErrMagic}
+	}
+
+	if b, err = read(4 * n); err != nil {
+		return nr, err
+	}
+	keys := make([]uint16, n)
+	cards := make([]int, n)
+	for i := range keys {
+		keys[i] = le.Uint16(b[4*i:])
+		cards[i] = int(le.Uint16(b[4*i+2:])) + 1
+		if i > 0 && keys[i] <= keys[i-1] {
+			return nr, &FormatError{nr - int64(len(b)) + 4*int64(i), ErrCorrupt}
+		}
+	}
+	isRun := func(i int) bool { return runs != nil && runs[i/8]>>(i%8)&1 != 0 }
+
+	if runs == nil || n >= noOffsetMax {
+		if b, err = read(4 * n); err != nil {
+			return nr, err
+		}
+		off := nr
+		for i := range keys {
+			if int64(le.Uint32(b[4*i:])) != off {
+				return nr, &FormatError{nr - int64(len(b)) + 4*int64(i), ErrCorrupt}
+			}
+			if isRun(i) {
+				// the size of a run container is only known once it is read, which
+				// makes the rest of the offsets impossible to check up front
+				break
+			}
+			if cards[i] <= arrayMax {
+				off += 2 * int64(cards[i])
+			} else {
+				off += bitmapBytes
+			}
+		}
+	}
+
+	cs := make([]*container, n)
+	for i := range cs {
+		start := nr
+		c := &container{card: cards[i]}
+		switch {
+		case isRun(i):
+			if b, err = read(2); err != nil {
+				return nr, err
+			}
+			nruns := int(le.Uint16(b))
+			if b, err = read(4 * nruns); err != nil {
+				return nr, err
+			}
+			c.kind, c.runs = runKind, make([]run16, nruns)
+			card := 0
+			for j := range c.runs {
+				first, length := le.Uint16(b[4*j:]), le.Uint16(b[4*j+2:])
+				if int(first)+int(length) >= bitmapBits || (j > 0 && int(first) <= int(c.runs[j-1].last)+1) {
+					return nr, &FormatError{start + 2 + 4*int64(j), ErrCorrupt}
+				}
+				c.runs[j] = run16{first, first + length}
+				card += int(length) + 1
+			}
+			if card != c.card {
+				return nr, &FormatError{start, ErrCorrupt}
+			}
+		case c.card <= arrayMax:
+			if b, err = read(2 * c.card); err != nil {
+				return nr, err
+			}
+			c.kind, c.arr = arrayKind, make([]uint16, c.card)
+			for j := range c.arr {
+				c.arr[j] = le.Uint16(b[2*j:])
+				if j > 0 && c.arr[j] <= c.arr[j-1] {
+					return nr, &FormatError{start + 2*int64(j), ErrCorrupt}
+				}
+			}
+		default:
+			if b, err = read(bitmapBytes); err != nil {
+				return nr, err
+			}
+			c.kind, c.bm = bitmapKind, New(bitmapBits)
+			for j := range c.bm.bits {
+				c.bm.bits[j] = le.Uint64(b[8*j:])
+			}
+			if c.bm.Cnt() != c.card {
+				return nr, &FormatError{start, ErrCorrupt}
+			}
+		}
+		cs[i] = c
+	}
+
+	r.keys, r.cs = keys, cs
+	return nr, nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, using the portable
+// Roaring serialization format.
+func (r *Roaring) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := r.WriteTo(&buf)
+	return buf.Bytes(), err
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface, using the portable
+// Roaring serialization format.
+func (r *Roaring) UnmarshalBinary(data []byte) error {
+	rd := bytes.NewReader(data)
+	nr, err := r.ReadFrom(rd)
+	if err == nil && rd.Len() != 0 {
+		err = &FormatError{nr, ErrCorrupt}
+	}
+	return err
+}
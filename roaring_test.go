@@ -0,0 +1,282 @@
+package bitarray
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+)
+
+// randomSet returns a random set of values mixing sparse chunks, dense chunks and runs.
+func randomSet(rng *rand.Rand) map[uint32]bool {
+	m := map[uint32]bool{}
+	for i := rng.Intn(6); i >= 0; i-- {
+		hi := uint32(rng.Intn(8)) << 16
+		switch rng.Intn(3) {
+		case 0: // sparse
+			for j := rng.Intn(100); j >= 0; j-- {
+				m[hi|uint32(rng.Intn(1<<16))] = true
+			}
+		case 1: // dense
+			for j := 0; j < 1<<16; j++ {
+				if rng.Intn(4) == 0 {
+					m[hi|uint32(j)] = true
+				}
+			}
+		case 2: // runs
+			for j := rng.Intn(10); j >= 0; j-- {
+				first := rng.Intn(1 << 16)
+				for k := first; k < min(first+rng.Intn(3000), 1<<16); k++ {
+					m[hi|uint32(k)] = true
+				}
+			}
+		}
+	}
+	return m
+}
+
+func roaringOf(m map[uint32]bool) *Roaring {
+	var r Roaring
+	for x := range m {
+		r.Add(x)
+	}
+	return &r
+}
+
+func checkRoaring(t *testing.T, r *Roaring, m map[uint32]bool) {
+	t.Helper()
+	if r.Cnt() != len(m) {
+		t.Fatalf("Test failed. got = %d, exp = %d\n", r.Cnt(), len(m))
+	}
+	exp := make([]uint32, 0, len(m))
+	for x := range m {
+		exp = append(exp, x)
+	}
+	slices.Sort(exp)
+	got := slices.Collect(r.Ones())
+	if !slices.Equal(got, exp) {
+		t.Fatalf("Test failed. got %d values, exp %d\n", len(got), len(exp))
+	}
+	for _, c := range r.cs {
+		if c.kind == arrayKind && c.card > arrayMax || c.kind == bitmapKind && c.card <= arrayMax {
+			t.Fatalf("Test failed. container of kind %d holds %d values\n", c.kind, c.card)
+		}
+	}
+}
+
+func TestRoaring(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	t.Run("add, remove and contains", func(t *testing.T) {
+		m := randomSet(rng)
+		r := roaringOf(m)
+		checkRoaring(t, r, m)
+
+		for x := range m {
+			if !r.Contains(x) {
+				t.Fatalf("Test failed. %d is missing\n", x)
+			}
+			if rng.Intn(2) == 0 {
+				r.Remove(x)
+				delete(m, x)
+			}
+		}
+		for i := 0; i < 1000; i++ {
+			x := uint32(rng.Intn(8 << 16))
+			if r.Contains(x) != m[x] {
+				t.Fatalf("Test failed. Contains(%d) = %t\n", x, r.Contains(x))
+			}
+		}
+		checkRoaring(t, r, m)
+	})
+
+	ops := []struct {
+		name string
+		fn   func(a, b *Roaring) *Roaring
+		ref  func(x, y bool) bool
+	}{
+		{"and", (*Roaring).And, func(x, y bool) bool { return x && y }},
+		{"or", (*Roaring).Or, func(x, y bool) bool { return x || y }},
+		{"xor", (*Roaring).Xor, func(x, y bool) bool { return x != y }},
+		{"andnot", (*Roaring).AndNot, func(x, y bool) bool { return x && !y }},
+	}
+
+	for _, op := range ops {
+		t.Run(op.name, func(t *testing.T) {
+			for i := 0; i < 10; i++ {
+				ma, mb := randomSet(rng), randomSet(rng)
+				a, b := roaringOf(ma), roaringOf(mb)
+				if rng.Intn(2) == 0 {
+					a.RunOptimize()
+				}
+				if rng.Intn(2) == 0 {
+					b.RunOptimize()
+				}
+
+				exp := map[uint32]bool{}
+				for x := range ma {
+					if op.ref(true, mb[x]) {
+						exp[x] = true
+					}
+				}
+				for x := range mb {
+					if op.ref(ma[x], true) {
+						exp[x] = true
+					}
+				}
+				checkRoaring(t, op.fn(a, b), exp)
+				// the operands must be left untouched
+				checkRoaring(t, a, ma)
+				checkRoaring(t, b, mb)
+			}
+		})
+	}
+
+	t.Run("run-optimize", func(t *testing.T) {
+		var r Roaring
+		for x := uint32(0); x < 100000; x++ {
+			r.Add(x)
+		}
+		r.RunOptimize()
+		for _, c := range r.cs {
+			if c.kind != runKind {
+				t.Fatalf("Test failed. got = %d, exp = %d\n", c.kind, runKind)
+			}
+		}
+		r.Remove(500)
+		r.Add(200000)
+		if r.Cnt() != 100000 || r.Contains(500) || !r.Contains(200000) || !r.Contains(501) {
+			t.Fatalf("Test failed. mutation of run containers\n")
+		}
+	})
+}
+
+func TestRoaringSerialization(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	t.Run("round-trip", func(t *testing.T) {
+		for i := 0; i < 20; i++ {
+			m := randomSet(rng)
+			r := roaringOf(m)
+			if i%2 == 0 {
+				r.RunOptimize()
+			}
+			data, err := r.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var d Roaring
+			if err := d.UnmarshalBinary(data); err != nil {
+				t.Fatal(err)
+			}
+			checkRoaring(t, &d, m)
+		}
+	})
+
+	// the expected bytes follow https://github.com/RoaringBitmap/RoaringFormatSpec
+	t.Run("golden, no runs", func(t *testing.T) {
+		var r Roaring
+		r.Add(1)
+		r.Add(2)
+		r.Add(1<<16 | 3)
+		exp := []byte{
+			0x3a, 0x30, 0, 0, // cookie
+			2, 0, 0, 0, // no. of containers
+			0, 0, 1, 0, // key 0, cardinality 2
+			1, 0, 0, 0, // key 1, cardinality 1
+			24, 0, 0, 0, // offset of container 0
+			28, 0, 0, 0, // offset of container 1
+			1, 0, 2, 0, // container 0
+			3, 0, // container 1
+		}
+		data, _ := r.MarshalBinary()
+		if !bytes.Equal(data, exp) {
+			t.Fatalf("Test failed. got = %x, exp = %x\n", data, exp)
+		}
+	})
+
+	t.Run("golden, runs", func(t *testing.T) {
+		var r Roaring
+		for x := uint32(0); x < 100; x++ {
+			r.Add(x)
+		}
+		r.RunOptimize()
+		exp := []byte{
+			0x3b, 0x30, 0, 0, // cookie, no. of containers - 1
+			1,           // run flags
+			0, 0, 99, 0, // key 0, cardinality 100
+			1, 0, 0, 0, 99, 0, // 1 run of 100 values starting at 0
+		}
+		data, _ := r.MarshalBinary()
+		if !bytes.Equal(data, exp) {
+			t.Fatalf("Test failed. got = %x, exp = %x\n", data, exp)
+		}
+	})
+
+	t.Run("golden, bitmap", func(t *testing.T) {
+		var r Roaring
+		for x := uint32(0); x < 1<<16; x += 2 {
+			r.Add(7<<16 | x)
+		}
+		data, _ := r.MarshalBinary()
+		if len(data) != 16+bitmapBytes || data[12] != 16 || data[16] != 0x55 {
+			t.Fatalf("Test failed. got = %x\n", data[:20])
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		var r Roaring
+		for x := uint32(0); x < 5; x++ {
+			r.Add(x << 16)
+		}
+		for x := uint32(0); x < 1000; x++ {
+			r.Add(9<<16 | x)
+		}
+		r.RunOptimize()
+		data, _ := r.MarshalBinary()
+
+		for k := 0; k < len(data); k++ {
+			var d Roaring
+			err := d.UnmarshalBinary(data[:k])
+			if !errors.Is(err, ErrTruncated) {
+				t.Fatalf("Test %d failed. got = %v, exp = %v\n", k, err, ErrTruncated)
+			}
+		}
+
+		var d Roaring
+		for _, data := range [][]byte{
+			{1, 2, 3, 4},
+			{0x3a, 0x30, 0, 0, 0, 0, 1, 0},
+			{0x3a, 0x30, 0, 0, 2, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 24, 0, 0, 0, 26, 0, 0, 0, 1, 0, 2, 0},
+			{0x3a, 0x30, 0, 0, 1, 0, 0, 0, 0, 0, 1, 0, 12, 0, 0, 0, 2, 0, 1, 0},
+			{0x3a, 0x30, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 9, 0, 0, 0, 1, 0},
+			append(bytes.Clone(data), 0),
+		} {
+			var fe *FormatError
+			if err := d.UnmarshalBinary(data); !errors.As(err, &fe) {
+				t.Fatalf("Test %x failed. got = %v, exp a *FormatError\n", data, err)
+			}
+		}
+	})
+}
+
+func BenchmarkRoaring(b *testing.B) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	x, y := roaringOf(randomSet(rng)), roaringOf(randomSet(rng))
+
+	b.Run("and", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			x.And(y)
+		}
+	})
+
+	b.Run("contains", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			x.Contains(uint32(i) & (8<<16 - 1))
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package bitarray
+
+// The predicates below treat an array or range as the set of positions of its set bits.
+// They work a block at a time and return as soon as a block decides the answer. As with
+// the bitwise operations, the missing bits of the shorter operand count as zero.
+
+// IsSubset reports whether every bit set in ba is also set in o.
+func (ba *BitArray) IsSubset(o *BitArray) bool {
+	for i := range ba.bits {
+		if ba.blk(i)&^o.blk(i) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSuperset reports whether every bit set in o is also set in ba.
+func (ba *BitArray) IsSuperset(o *BitArray) bool { return o.IsSubset(ba) }
+
+// Intersects reports whether some bit is set in both ba and o.
+func (ba *BitArray) Intersects(o *BitArray) bool {
+	for i, n := 0, min(len(ba.bits), len(o.bits)); i < n; i++ {
+		if ba.blk(i)&o.blk(i) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDisjoint reports whether no bit is set in both ba and o.
+func (ba *BitArray) IsDisjoint(o *BitArray) bool { return !ba.Intersects(o) }
+
+// IsEmpty reports whether no bit is set.
+func (ba *BitArray) IsEmpty() bool {
+	for i := range ba.bits {
+		if ba.blk(i) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// IsFull reports whether every bit is set.
+func (ba *BitArray) IsFull() bool {
+	if ba.n == 0 {
+		return true
+	}
+	last := len(ba.bits) - 1
+	for _, u := range ba.bits[:last] {
+		if u != ^Bit(0) {
+			return false
+		}
+	}
+	return ba.blk(last) == lomask(ba.n-64*last)
+}
+
+// IsSubset reports whether every bit set in r is also set in o.
+func (r Range) IsSubset(o Range) bool { return subset(r.ba.bits, r.b, r.n, o.ba.bits, o.b, o.n) }
+
+// IsSuperset reports whether every bit set in o is also set in r.
+func (r Range) IsSuperset(o Range) bool { return o.IsSubset(r) }
+
+// Intersects reports whether some bit is set in both r and o.
+func (r Range) Intersects(o Range) bool {
+	return intersects(r.ba.bits, r.b, r.n, o.ba.bits, o.b, o.n)
+}
+
+// IsDisjoint reports whether no bit is set in both r and o.
+func (r Range) IsDisjoint(o Range) bool { return !r.Intersects(o) }
+
+// IsEmpty reports whether no bit in the range is set.
+func (r Range) IsEmpty() bool { return empty(r.ba.bits, r.b, r.n) }
+
+// IsFull reports whether every bit in the range is set.
+func (r Range) IsFull() bool { return full(r.ba.bits, r.b, r.n) }
+
+// subset reports whether the bits set among the an bits of a starting at bit position ak
+// are all set among the bn bits of b starting at bk.
+func subset(a []Bit, ak, an int, b []Bit, bk, bn int) bool {
+	for k := 0; k < an; k += 64 {
+		w := min(64, an-k)
+		var v Bit
+		if k < bn {
+			v = getbits(b, bk+k, min(w, bn-k))
+		}
+		if getbits(a, ak+k, w)&^v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// intersects reports whether a bit is set both among the an bits of a starting at bit
+// position ak and the bn bits of b starting at bk.
+func intersects(a []Bit, ak, an int, b []Bit, bk, bn int) bool {
+	n := min(an, bn)
+	for k := 0; k < n; k += 64 {
+		w := min(64, n-k)
+		if getbits(a, ak+k, w)&getbits(b, bk+k, w) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// empty reports whether none of the n bits of s starting at bit position k is set.
+func empty(s []Bit, k, n int) bool {
+	for j := 0; j < n; j += 64 {
+		if getbits(s, k+j, min(64, n-j)) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// full reports whether all the n bits of s starting at bit position k are set.
+func full(s []Bit, k, n int) bool {
+	for j := 0; j < n; j += 64 {
+		w := min(64, n-j)
+		if getbits(s, k+j, w) != lomask(w) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,147 @@
+package bitarray
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowSubset is the reference IsSubset, on the string forms.
+func slowSubset(a, b string) bool {
+	for k := range a {
+		if a[k] == '1' && (k >= len(b) || b[k] == '0') {
+			return false
+		}
+	}
+	return true
+}
+
+// slowIntersects is the reference Intersects, on the string forms.
+func slowIntersects(a, b string) bool {
+	for k := 0; k < min(len(a), len(b)); k++ {
+		if a[k] == '1' && b[k] == '1' {
+			return true
+		}
+	}
+	return false
+}
+
+// sprinkle sets about one in `every` bits of ba.
+func sprinkle(ba *BitArray, every int, rng *rand.Rand) {
+	for k := 0; k < ba.n; k++ {
+		if rng.Intn(every) == 0 {
+			ba.Set(k)
+		}
+	}
+}
+
+func TestSets(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	chk := func(t *testing.T, as, bs string, sub, sup, in, dis, ae, af bool) {
+		t.Helper()
+		for _, tt := range []struct {
+			name     string
+			got, exp bool
+		}{
+			{"IsSubset", sub, slowSubset(as, bs)},
+			{"IsSuperset", sup, slowSubset(bs, as)},
+			{"Intersects", in, slowIntersects(as, bs)},
+			{"IsDisjoint", dis, !slowIntersects(as, bs)},
+			{"IsEmpty", ae, !strings.Contains(as, "1")},
+			{"IsFull", af, !strings.Contains(as, "0")},
+		} {
+			if tt.got != tt.exp {
+				t.Fatalf("Test %s failed. got = %t, exp = %t\na = %s\nb = %s\n", tt.name, tt.got, tt.exp, as, bs)
+			}
+		}
+	}
+
+	t.Run("arrays", func(t *testing.T) {
+		for i := 0; i < 5000; i++ {
+			a := New(rng.Intn(300))
+			b := New(rng.Intn(300))
+			switch rng.Intn(4) {
+			case 0:
+				sprinkle(&a, 100, rng)
+				sprinkle(&b, 100, rng)
+			case 1:
+				// b holds a, so a is a subset of b where they overlap
+				sprinkle(&a, 50, rng)
+				if a.n != 0 && b.n != 0 {
+					CopyRange(b.Range(0, b.n), a.Range(0, a.n))
+				}
+				sprinkle(&b, 20, rng)
+			case 2:
+				a.SetAll()
+				if a.n != 0 && rng.Intn(2) == 0 {
+					a.Clr(rng.Intn(a.n))
+				}
+				sprinkle(&b, 2, rng)
+			}
+			as, bs := a.String(), b.String()
+			chk(t, as, bs, a.IsSubset(&b), a.IsSuperset(&b), a.Intersects(&b), a.IsDisjoint(&b), a.IsEmpty(), a.IsFull())
+		}
+	})
+
+	t.Run("ranges", func(t *testing.T) {
+		a, b := New(400), New(400)
+		for i := 0; i < 5000; i++ {
+			a.ClrAll()
+			b.ClrAll()
+			ab, an := rng.Intn(200), rng.Intn(200)
+			bb, bn := rng.Intn(200), rng.Intn(200)
+			ra, rb := a.Range(ab, an), b.Range(bb, bn)
+			switch rng.Intn(4) {
+			case 0:
+				sprinkle(&a, 100, rng)
+				sprinkle(&b, 100, rng)
+			case 1:
+				sprinkle(&a, 50, rng)
+				CopyRange(rb, ra)
+				sprinkle(&b, 20, rng)
+			case 2:
+				sprinkle(&a, 2, rng)
+				ra.SetAll()
+				if an != 0 && rng.Intn(2) == 0 {
+					ra.Clr(rng.Intn(an))
+				}
+			}
+			as, bs := ra.String(), rb.String()
+			chk(t, as, bs, ra.IsSubset(rb), ra.IsSuperset(rb), ra.Intersects(rb), ra.IsDisjoint(rb), ra.IsEmpty(), ra.IsFull())
+		}
+	})
+
+	t.Run("padding", func(t *testing.T) {
+		a, b, e := New(10), New(10), New(0)
+		a.bits[0] |= 1 << 20
+		b.SetAll()
+		b.bits[0] |= 1 << 30
+		if !a.IsEmpty() || !a.IsSubset(&e) || a.Intersects(&b) || !b.IsFull() {
+			t.Fatalf("Test failed. the padding bits were looked at\n")
+		}
+	})
+}
+
+func BenchmarkSets(b *testing.B) {
+	x, y := New(1<<16), New(1<<16)
+	x.Set(x.n - 1)
+	y.Set(0)
+
+	b.Run("intersects", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			x.Intersects(&y)
+		}
+	})
+
+	b.Run("and-cnt", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			z := x.Clone()
+			z.And(&y)
+			_ = z.Cnt() != 0
+		}
+	})
+}
@@ -0,0 +1,187 @@
+package bitarray
+
+// Shifts move bits towards higher (Lsh) or lower (Rsh) positions, the same way
+// the shift operators do on the uint64 returned by FromUint64: after Lsh(k), the
+// bit at position i is found at position i+k. Bits shifted past either end are
+// discarded and the vacated positions are cleared. Rotations move the bits that
+// fall off one end back in at the other. They work in place: the bits of a small
+// rotation are saved in a buffer of rotBlks blocks on the stack, and larger ones are
+// done by reversing the bits three times.
+
+// rotBlks is the no. of blocks of the buffer that small rotations save bits in.
+const rotBlks = 8
+
+// Lsh shifts the bits towards higher positions by k.
+func (ba *BitArray) Lsh(k int) {
+	if k < 0 {
+		panic("negative shift amount")
+	}
+	if k >= ba.n {
+		ba.ClrAll()
+		return
+	}
+
+	m, n := k/64, uint(k%64)
+	for i := len(ba.bits) - 1; i >= m; i-- {
+		u := ba.bits[i-m] << n
+		if n != 0 && i-m > 0 {
+			u |= ba.bits[i-m-1] >> (64 - n)
+		}
+		ba.bits[i] = u
+	}
+	clear(ba.bits[:m])
+	ba.clrTail()
+}
+
+// Rsh shifts the bits towards lower positions by k.
+func (ba *BitArray) Rsh(k int) {
+	if k < 0 {
+		panic("negative shift amount")
+	}
+	if k >= ba.n {
+		ba.ClrAll()
+		return
+	}
+
+	m, n := k/64, uint(k%64)
+	nblk := len(ba.bits)
+	for i := 0; i < nblk-m; i++ {
+		u := ba.blk(i+m) >> n
+		if n != 0 {
+			u |= ba.blk(i+m+1) << (64 - n)
+		}
+		ba.bits[i] = u
+	}
+	clear(ba.bits[nblk-m:])
+}
+
+// RotL rotates the bits towards higher positions by k. A negative k rotates the other way.
+func (ba *BitArray) RotL(k int) {
+	if ba.n == 0 {
+		return
+	}
+	k = rotamt(k, ba.n)
+	if k > ba.n/2 {
+		ba.RotR(ba.n - k)
+		return
+	}
+	if k == 0 {
+		return
+	}
+
+	if k > 64*rotBlks {
+		rotbits(ba.bits, 0, ba.n, k)
+		return
+	}
+	// save the bits that fall off the top end
+	var t [rotBlks]Bit
+	movebits(t[:], 0, ba.bits, ba.n-k, k)
+	ba.Lsh(k)
+	movebits(ba.bits, 0, t[:], 0, k)
+}
+
+// RotR rotates the bits towards lower positions by k. A negative k rotates the other way.
+func (ba *BitArray) RotR(k int) {
+	if ba.n == 0 {
+		return
+	}
+	k = rotamt(k, ba.n)
+	if k > ba.n/2 {
+		ba.RotL(ba.n - k)
+		return
+	}
+	if k == 0 {
+		return
+	}
+
+	if k > 64*rotBlks {
+		rotbits(ba.bits, 0, ba.n, ba.n-k)
+		return
+	}
+	// save the bits that fall off the bottom end
+	var t [rotBlks]Bit
+	movebits(t[:], 0, ba.bits, 0, k)
+	ba.Rsh(k)
+	movebits(ba.bits, ba.n-k, t[:], 0, k)
+}
+
+// Lsh shifts the bits of the range towards higher positions by k.
+// Bits outside the range are not affected.
+func (r Range) Lsh(k int) {
+	if k < 0 {
+		panic("negative shift amount")
+	}
+	k = min(k, r.n)
+	movebits(r.ba.bits, r.b+k, r.ba.bits, r.b, r.n-k)
+	fillbits(r.ba.bits, r.b, k, Zero)
+}
+
+// Rsh shifts the bits of the range towards lower positions by k.
+// Bits outside the range are not affected.
+func (r Range) Rsh(k int) {
+	if k < 0 {
+		panic("negative shift amount")
+	}
+	k = min(k, r.n)
+	movebits(r.ba.bits, r.b, r.ba.bits, r.b+k, r.n-k)
+	fillbits(r.ba.bits, r.b+r.n-k, k, Zero)
+}
+
+// RotL rotates the bits of the range towards higher positions by k.
+// A negative k rotates the other way.
+func (r Range) RotL(k int) {
+	if r.n == 0 {
+		return
+	}
+	k = rotamt(k, r.n)
+	if k > r.n/2 {
+		r.RotR(r.n - k)
+		return
+	}
+	if k == 0 {
+		return
+	}
+
+	if k > 64*rotBlks {
+		rotbits(r.ba.bits, r.b, r.n, k)
+		return
+	}
+	var t [rotBlks]Bit
+	movebits(t[:], 0, r.ba.bits, r.b+r.n-k, k)
+	r.Lsh(k)
+	movebits(r.ba.bits, r.b, t[:], 0, k)
+}
+
+// RotR rotates the bits of the range towards lower positions by k.
+// A negative k rotates the other way.
+func (r Range) RotR(k int) {
+	if r.n == 0 {
+		return
+	}
+	k = rotamt(k, r.n)
+	if k > r.n/2 {
+		r.RotL(r.n - k)
+		return
+	}
+	if k == 0 {
+		return
+	}
+
+	if k > 64*rotBlks {
+		rotbits(r.ba.bits, r.b, r.n, r.n-k)
+		return
+	}
+	var t [rotBlks]Bit
+	movebits(t[:], 0, r.ba.bits, r.b, k)
+	r.Rsh(k)
+	movebits(r.ba.bits, r.b+r.n-k, t[:], 0, k)
+}
+
+// rotamt reduces the rotation amount k to [0, n).
+func rotamt(k, n int) int {
+	k %= n
+	if k < 0 {
+		k += n
+	}
+	return k
+}
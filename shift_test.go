@@ -0,0 +1,202 @@
+package bitarray
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowLsh shifts the bit string s towards higher positions by k.
+func slowLsh(s string, k int) string {
+	k = min(k, len(s))
+	return strings.Repeat("0", k) + s[:len(s)-k]
+}
+
+// slowRsh shifts the bit string s towards lower positions by k.
+func slowRsh(s string, k int) string {
+	k = min(k, len(s))
+	return s[k:] + strings.Repeat("0", k)
+}
+
+// slowRotL rotates the bit string s towards higher positions by k.
+func slowRotL(s string, k int) string {
+	if len(s) == 0 {
+		return s
+	}
+	k = rotamt(k, len(s))
+	return s[len(s)-k:] + s[:len(s)-k]
+}
+
+func TestShift(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	t.Run("fromuint64", func(t *testing.T) {
+		const u = uint64(0xfedcba9876543210)
+		for k := 0; k < 64; k++ {
+			l, r := FromUint64(u), FromUint64(u)
+			l.Lsh(k)
+			r.Rsh(k)
+			if l.bits[0] != u<<k {
+				t.Fatalf("Test Lsh(%d) failed. got = %x, exp = %x\n", k, l.bits[0], u<<k)
+			}
+			if r.bits[0] != u>>k {
+				t.Fatalf("Test Rsh(%d) failed. got = %x, exp = %x\n", k, r.bits[0], u>>k)
+			}
+		}
+	})
+
+	for _, n := range []int{0, 1, 5, 63, 64, 65, 130, 257, 700, 2000} {
+		ba := New(n)
+		randomize(&ba, rng)
+		s := ba.String()
+		for _, k := range []int{0, 1, 3, 31, 63, 64, 65, 100, 128, 256, 257, 513, 699, 700, 999, 1000, 1300} {
+			b := New(n)
+			Copy(&b, &ba)
+			b.Lsh(k)
+			if exp := slowLsh(s, k); b.String() != exp {
+				t.Fatalf("Test Lsh(%d) of %d bits failed. got = %s\nexp = %s\n", k, n, b.String(), exp)
+			}
+
+			Copy(&b, &ba)
+			b.Rsh(k)
+			if exp := slowRsh(s, k); b.String() != exp {
+				t.Fatalf("Test Rsh(%d) of %d bits failed. got = %s\nexp = %s\n", k, n, b.String(), exp)
+			}
+			if b.Cnt() != strings.Count(b.String(), "1") {
+				t.Fatalf("Test Rsh(%d) of %d bits failed. garbage in unused bits\n", k, n)
+			}
+
+			Copy(&b, &ba)
+			b.RotL(k)
+			if exp := slowRotL(s, k); b.String() != exp {
+				t.Fatalf("Test RotL(%d) of %d bits failed. got = %s\nexp = %s\n", k, n, b.String(), exp)
+			}
+
+			Copy(&b, &ba)
+			b.RotR(k)
+			if exp := slowRotL(s, -k); b.String() != exp {
+				t.Fatalf("Test RotR(%d) of %d bits failed. got = %s\nexp = %s\n", k, n, b.String(), exp)
+			}
+		}
+	}
+}
+
+func TestShiftRange(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	ops := []struct {
+		name string
+		fn   func(r Range, k int)
+		ref  func(s string, k int) string
+	}{
+		{"lsh", Range.Lsh, slowLsh},
+		{"rsh", Range.Rsh, slowRsh},
+		{"rotl", Range.RotL, slowRotL},
+		{"rotr", Range.RotR, func(s string, k int) string { return slowRotL(s, -k) }},
+	}
+
+	for _, op := range ops {
+		t.Run(op.name, func(t *testing.T) {
+			for i := 0; i < 500; i++ {
+				ba := New(1 + rng.Intn(2000))
+				randomize(&ba, rng)
+				b := rng.Intn(ba.n)
+				n := rng.Intn(ba.n - b + 1)
+				k := rng.Intn(n + 70)
+
+				s := ba.String()
+				exp := s[:b] + op.ref(s[b:b+n], k) + s[b+n:]
+				op.fn(ba.Range(b, n), k)
+				if ba.String() != exp {
+					t.Fatalf("Test (%d, %d) by %d failed. got = %s\nexp = %s\n", b, n, k, ba.String(), exp)
+				}
+			}
+		})
+	}
+}
+
+func TestRevBits(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	ba := New(400)
+	for k := 0; k < 70; k++ {
+		for n := 0; n <= ba.n-k; n++ {
+			randomize(&ba, rng)
+			s := ba.String()
+			r := []byte(s[k : k+n])
+			for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+				r[i], r[j] = r[j], r[i]
+			}
+			exp := s[:k] + string(r) + s[k+n:]
+			revbits(ba.bits, k, n)
+			if ba.String() != exp {
+				t.Fatalf("Test revbits(%d, %d) failed. got = %s\nexp = %s\n", k, n, ba.String(), exp)
+			}
+		}
+	}
+}
+
+func BenchmarkShift(b *testing.B) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	b.Run("lsh", func(b *testing.B) {
+		b.ReportAllocs()
+		b.StopTimer()
+		ba := New(4096)
+		randomize(&ba, rng)
+		b.StartTimer()
+
+		for i := 0; i < b.N; i++ {
+			ba.Lsh(3)
+		}
+	})
+
+	b.Run("rotl", func(b *testing.B) {
+		b.ReportAllocs()
+		b.StopTimer()
+		ba := New(4096)
+		randomize(&ba, rng)
+		b.StartTimer()
+
+		for i := 0; i < b.N; i++ {
+			ba.RotL(3)
+		}
+	})
+
+	b.Run("rotl, large", func(b *testing.B) {
+		b.ReportAllocs()
+		b.StopTimer()
+		ba := New(4096)
+		randomize(&ba, rng)
+		b.StartTimer()
+
+		for i := 0; i < b.N; i++ {
+			ba.RotL(1500)
+		}
+	})
+
+	b.Run("range rotl", func(b *testing.B) {
+		b.ReportAllocs()
+		b.StopTimer()
+		ba := New(4096)
+		randomize(&ba, rng)
+		r := ba.Range(47, 4000)
+		b.StartTimer()
+
+		for i := 0; i < b.N; i++ {
+			r.RotL(300)
+		}
+	})
+
+	b.Run("range lsh", func(b *testing.B) {
+		b.ReportAllocs()
+		b.StopTimer()
+		ba := New(4096)
+		randomize(&ba, rng)
+		r := ba.Range(47, 4000)
+		b.StartTimer()
+
+		for i := 0; i < b.N; i++ {
+			r.Lsh(3)
+		}
+	})
+}
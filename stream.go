@@ -0,0 +1,156 @@
+package bitarray
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+// The file format written by WriteTo and read by ReadFrom is laid out as follows,
+// with all integers little-endian:
+//
+//	offset  size  field
+//	0       4     magic "BITA"
+//	4       2     format version, currently 1
+//	6       2     reserved, zero
+//	8       8     no. of bits n
+//	16      4     CRC-32C of bytes 0..15
+//	20      8*m   the m = ceil(n/64) blocks, as little-endian uint64s
+//	20+8*m  4     CRC-32C of the blocks
+//
+// The unused bits of the last block are zero.
+
+const (
+	fileMagic   = "BITA"
+	fileVersion = 1
+	fileHdrLen  = 20
+)
+
+var (
+	// ErrMagic is returned when the data does not start with the file magic.
+	ErrMagic = errors.New("bitarray: not a bit array file")
+	// ErrVersion is returned for a file written in an unsupported version of the format.
+	ErrVersion = errors.New("bitarray: unsupported file version")
+	// ErrTruncated is returned when the data ends before the whole file is read.
+	ErrTruncated = errors.New("bitarray: truncated file")
+	// ErrChecksum is returned when a checksum stored in the file does not match its contents.
+	ErrChecksum = errors.New("bitarray: checksum mismatch")
+	// ErrCorrupt is returned when the file is otherwise malformed.
+	ErrCorrupt = errors.New("bitarray: corrupt file")
+)
+
+// A FormatError reports a problem with the data read by ReadFrom.
+// Err is one of ErrMagic, ErrVersion, ErrTruncated, ErrChecksum or ErrCorrupt.
+type FormatError struct {
+	Offset int64 // offset into the file at which the problem was found
+	Err    error
+}
+
+func (e *FormatError) Error() string { return fmt.Sprintf("%v at offset %d", e.Err, e.Offset) }
+
+func (e *FormatError) Unwrap() error { return e.Err }
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// WriteTo implements the io.WriterTo interface. It writes the array to w in the file
+// format described above, streaming the blocks through a small fixed-size buffer.
+func (ba *BitArray) WriteTo(w io.Writer) (int64, error) {
+	var hdr [fileHdrLen]byte
+	copy(hdr[:], fileMagic)
+	binary.LittleEndian.PutUint16(hdr[4:], fileVersion)
+	binary.LittleEndian.PutUint64(hdr[8:], uint64(ba.n))
+	binary.LittleEndian.PutUint32(hdr[16:], crc32.Checksum(hdr[:16], castagnoli))
+	k, err := w.Write(hdr[:])
+	nw := int64(k)
+	if err != nil {
+		return nw, err
+	}
+
+	var buf [4096]byte
+	var crc uint32
+	for i := 0; i < len(ba.bits); {
+		b := buf[:0]
+		for ; i < len(ba.bits) && len(b) < len(buf); i++ {
+			b = binary.LittleEndian.AppendUint64(b, ba.blk(i))
+		}
+		crc = crc32.Update(crc, castagnoli, b)
+		k, err = w.Write(b)
+		nw += int64(k)
+		if err != nil {
+			return nw, err
+		}
+	}
+
+	binary.LittleEndian.PutUint32(buf[:4], crc)
+	k, err = w.Write(buf[:4])
+	return nw + int64(k), err
+}
+
+// ReadFrom implements the io.ReaderFrom interface. It replaces the contents of the array
+// with an array read from r in the file format described above. It reads exactly one array,
+// leaving any data that follows it in r. Problems with the data are reported as a *FormatError.
+// The blocks are decoded into storage that grows as they arrive, so a header claiming
+// more bits than the data holds fails with ErrTruncated instead of allocating them all.
+// On error, the array is left unchanged.
+func (ba *BitArray) ReadFrom(r io.Reader) (int64, error) {
+	var nr int64
+	read := func(b []byte) error {
+		k, err := io.ReadFull(r, b)
+		nr += int64(k)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return &FormatError{nr, ErrTruncated}
+		}
+		return err
+	}
+
+	var hdr [fileHdrLen]byte
+	if err := read(hdr[:]); err != nil {
+		return nr, err
+	}
+	switch {
+	case string(hdr[:4]) != fileMagic:
+		return nr, &FormatError{0, ErrMagic}
+	case binary.LittleEndian.Uint32(hdr[16:]) != crc32.Checksum(hdr[:16], castagnoli):
+		return nr, &FormatError{16, ErrChecksum}
+	case binary.LittleEndian.Uint16(hdr[4:]) != fileVersion:
+		return nr, &FormatError{4, ErrVersion}
+	case binary.LittleEndian.Uint16(hdr[6:]) != 0:
+		return nr, &FormatError{6, ErrCorrupt}
+	}
+	u := binary.LittleEndian.Uint64(hdr[8:])
+	if u > math.MaxInt-63 {
+		return nr, &FormatError{8, ErrCorrupt}
+	}
+
+	n := int(u)
+	m := (n + 63) / 64 // no. of blocks
+
+	var buf [4096]byte
+	var crc uint32
+	var bits []Bit
+	for len(bits) < m {
+		b := buf[:min(len(buf), 8*(m-len(bits)))]
+		if err := read(b); err != nil {
+			return nr, err
+		}
+		crc = crc32.Update(crc, castagnoli, b)
+		for ; len(b) != 0; b = b[8:] {
+			bits = append(bits, binary.LittleEndian.Uint64(b))
+		}
+	}
+
+	if err := read(buf[:4]); err != nil {
+		return nr, err
+	}
+	if binary.LittleEndian.Uint32(buf[:4]) != crc {
+		return nr, &FormatError{nr - 4, ErrChecksum}
+	}
+	if si := n % 64; si != 0 && bits[m-1]>>si != 0 {
+		return nr, &FormatError{nr - 12, ErrCorrupt}
+	}
+	ba.bits, ba.n = bits, n
+	return nr, nil
+}
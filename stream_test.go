@@ -0,0 +1,155 @@
+package bitarray
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestStream(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	t.Run("round-trip", func(t *testing.T) {
+		for _, n := range []int{0, 1, 63, 64, 65, 257, 512, 40000, 100003} {
+			src := New(n)
+			randomize(&src, rng)
+
+			var buf bytes.Buffer
+			nw, err := src.WriteTo(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exp := int64(fileHdrLen + 8*len(src.bits) + 4); nw != exp || int64(buf.Len()) != exp {
+				t.Fatalf("Test %d failed. wrote %d bytes, buffered %d, exp = %d\n", n, nw, buf.Len(), exp)
+			}
+
+			// trailing data must be left unread
+			buf.WriteString("tail")
+			var dst BitArray
+			nr, err := dst.ReadFrom(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if nr != nw {
+				t.Fatalf("Test %d failed. read %d bytes, exp = %d\n", n, nr, nw)
+			}
+			if dst.Size() != n || dst.String() != src.String() {
+				t.Fatalf("Test %d failed. got = %s\nexp = %s\n", n, dst.String(), src.String())
+			}
+			if buf.String() != "tail" {
+				t.Fatalf("Test %d failed. got = %q, exp = %q\n", n, buf.String(), "tail")
+			}
+		}
+	})
+
+	src := New(150)
+	randomize(&src, rng)
+	var buf bytes.Buffer
+	src.WriteTo(&buf)
+	data := buf.Bytes()
+
+	t.Run("truncated", func(t *testing.T) {
+		for k := 0; k < len(data); k++ {
+			var dst BitArray
+			_, err := dst.ReadFrom(bytes.NewReader(data[:k]))
+			var fe *FormatError
+			if !errors.As(err, &fe) || !errors.Is(err, ErrTruncated) {
+				t.Fatalf("Test %d failed. got = %v, exp = %v\n", k, err, ErrTruncated)
+			}
+		}
+	})
+
+	t.Run("huge size, no blocks", func(t *testing.T) {
+		// a valid header claiming 2^46 bits, followed by nothing
+		hdr := bytes.Clone(data[:fileHdrLen])
+		binary.LittleEndian.PutUint64(hdr[8:], 1<<46)
+		binary.LittleEndian.PutUint32(hdr[16:], crc32.Checksum(hdr[:16], castagnoli))
+		dst := FromStr("101")
+		_, err := dst.ReadFrom(bytes.NewReader(hdr))
+		if !errors.Is(err, ErrTruncated) {
+			t.Fatalf("Test failed. got = %v, exp = %v\n", err, ErrTruncated)
+		}
+		if dst.String() != "101" {
+			t.Fatalf("Test failed. the array changed on error, got = %s\n", dst.String())
+		}
+	})
+
+	t.Run("unchanged on error", func(t *testing.T) {
+		b := bytes.Clone(data)
+		b[len(b)-1] ^= 1 // the blocks' checksum
+		dst := FromStr("0110")
+		if _, err := dst.ReadFrom(bytes.NewReader(b)); !errors.Is(err, ErrChecksum) {
+			t.Fatalf("Test failed. got = %v, exp = %v\n", err, ErrChecksum)
+		}
+		if dst.String() != "0110" {
+			t.Fatalf("Test failed. the array changed on error, got = %s\n", dst.String())
+		}
+	})
+
+	t.Run("corrupted", func(t *testing.T) {
+		for k := 0; k < len(data); k++ {
+			for _, x := range []byte{0x01, 0x80} {
+				b := bytes.Clone(data)
+				b[k] ^= x
+				var dst BitArray
+				_, err := dst.ReadFrom(bytes.NewReader(b))
+				var fe *FormatError
+				if !errors.As(err, &fe) {
+					t.Fatalf("Test byte %d ^ %x failed. got = %v, exp a *FormatError\n", k, x, err)
+				}
+			}
+		}
+
+		var dst BitArray
+		_, err := dst.ReadFrom(bytes.NewReader([]byte("not a bit array file")))
+		if !errors.Is(err, ErrMagic) {
+			t.Fatalf("Test failed. got = %v, exp = %v\n", err, ErrMagic)
+		}
+	})
+
+	t.Run("reader error", func(t *testing.T) {
+		var dst BitArray
+		_, err := dst.ReadFrom(io.MultiReader(bytes.NewReader(data[:30]), errReader{}))
+		if err != errRead {
+			t.Fatalf("Test failed. got = %v, exp = %v\n", err, errRead)
+		}
+	})
+}
+
+var errRead = errors.New("read error")
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errRead }
+
+func BenchmarkStream(b *testing.B) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	ba := New(1 << 20)
+	randomize(&ba, rng)
+
+	b.Run("write", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(ba.n / 8))
+		for i := 0; i < b.N; i++ {
+			ba.WriteTo(io.Discard)
+		}
+	})
+
+	b.Run("read", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(ba.n / 8))
+		var buf bytes.Buffer
+		ba.WriteTo(&buf)
+		r := bytes.NewReader(buf.Bytes())
+		var dst BitArray
+		for i := 0; i < b.N; i++ {
+			r.Seek(0, io.SeekStart)
+			dst.ReadFrom(r)
+		}
+	})
+}
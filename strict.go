@@ -0,0 +1,6 @@
+//go:build bitarray_strict
+
+package bitarray
+
+// strict turns on the bounds and invariant checks of assertIdx and assertInvariants.
+const strict = true
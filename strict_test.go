@@ -0,0 +1,34 @@
+//go:build bitarray_strict
+
+package bitarray
+
+import "testing"
+
+func TestStrict(t *testing.T) {
+	for name, fn := range map[string]func(ba *BitArray){
+		"set padding":    func(ba *BitArray) { ba.Set(70) },
+		"chk padding":    func(ba *BitArray) { ba.Chk(100) },
+		"put negative":   func(ba *BitArray) { ba.Put(-1, One) },
+		"swap past end":  func(ba *BitArray) { v := One; ba.Swap(128, &v) },
+		"cnt, tail set":  func(ba *BitArray) { ba.bits[1] |= 1 << 63; ba.Cnt() },
+		"cnt, bad size":  func(ba *BitArray) { ba.bits = ba.bits[:1]; ba.Cnt() },
+		"copy, tail set": func(ba *BitArray) { ba.bits[1] |= 1 << 6; dst := New(70); Copy(&dst, ba) },
+		"atomic set":     func(ba *BitArray) { ba.AtomicSet(70) },
+		"atomic clr":     func(ba *BitArray) { ba.AtomicClr(-1) },
+		"atomic tgl":     func(ba *BitArray) { ba.AtomicTgl(127) },
+		"atomic chk":     func(ba *BitArray) { ba.AtomicChk(100) },
+		"atomic chkset":  func(ba *BitArray) { ba.AtomicChkSet(70) },
+		"atomic chkclr":  func(ba *BitArray) { ba.AtomicChkClr(70) },
+		"cas":            func(ba *BitArray) { ba.CompareAndSwapBit(70, Zero, One) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			ba := New(70)
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("Test failed. expected a panic\n")
+				}
+			}()
+			fn(&ba)
+		})
+	}
+}